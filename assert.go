@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// countOperators are the comparison operators of -assert-count, longest first so that >= isn't read as >.
+var countOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// countAssertion is a parsed -assert-count expression, e.g. >100.
+type countAssertion struct {
+	operator string
+	expected int
+}
+
+// parseCountAssertion parses a comparison operator followed by the expected count.
+func parseCountAssertion(expression string) (*countAssertion, error) {
+	expression = strings.TrimSpace(expression)
+	for _, operator := range countOperators {
+		if !strings.HasPrefix(expression, operator) {
+			continue
+		}
+		expected, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(expression, operator)))
+		if err != nil {
+			break
+		}
+		return &countAssertion{operator: operator, expected: expected}, nil
+	}
+	return nil, fmt.Errorf("invalid count assertion %q, expected an operator of %s followed by a count, e.g. >100", expression, strings.Join(countOperators, " "))
+}
+
+// holds reports whether the count satisfies the assertion.
+func (a *countAssertion) holds(count int) bool {
+	switch a.operator {
+	case ">=":
+		return count >= a.expected
+	case "<=":
+		return count <= a.expected
+	case "!=":
+		return count != a.expected
+	case ">":
+		return count > a.expected
+	case "<":
+		return count < a.expected
+	default:
+		return count == a.expected
+	}
+}
+
+// String formats the assertion as an expression.
+func (a *countAssertion) String() string {
+	return fmt.Sprintf("%s%d", a.operator, a.expected)
+}
+
+// runAssertCount counts the records matching the query and checks the count against the
+// -assert-count expression, exiting unsuccessfully when it doesn't hold.
+func runAssertCount(ctx context.Context, opts *options, databaseClient *DatabaseClient, endpoint string, query string) {
+	assertion, err := parseCountAssertion(opts.assertCount)
+	if err != nil {
+		handleErr("failed to assert the count", err, BAD_USAGE_EXIT_CODE)
+	}
+	if endpoint == MULTIQUERY_ENDPOINT {
+		handleErr("failed to assert the count", fmt.Errorf("-assert-count is not supported for the %s endpoint", endpoint), BAD_USAGE_EXIT_CODE)
+	}
+	q, err := parseQuery(query)
+	if err != nil {
+		handleErr("failed to assert the count", fmt.Errorf("failed to parse query: %s", err.Error()), BAD_USAGE_EXIT_CODE)
+	}
+
+	count, err := countRecords(ctx, databaseClient, strings.TrimSuffix(endpoint, COUNT_ENDPOINT_SUFFIX), q)
+	if err != nil {
+		explainRateLimit(opts, databaseClient)
+		handleQueryErr(opts, "failed to count the records", err)
+	}
+
+	if !assertion.holds(count) {
+		failAssertion(&assertionError{fmt.Sprintf("%s has %d matching records, expected %s", endpoint, count, assertion.String())})
+	}
+	printfStdout("%s has %d matching records, satisfying %s\n", endpoint, count, assertion.String())
+}
+
+// newestUpdatedAt returns the most recent updated_at of the records in the result, reporting
+// whether any record has one.
+func newestUpdatedAt(result string) (time.Time, bool, error) {
+	records, err := decodeRecords(result)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to decode records: %s", err.Error())
+	}
+
+	var newest int64
+	found := false
+	for _, record := range records {
+		number, ok := record["updated_at"].(json.Number)
+		if !ok {
+			continue
+		}
+		updatedAt, err := number.Int64()
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid updated_at %s: %s", number, err.Error())
+		}
+		if !found || updatedAt > newest {
+			newest = updatedAt
+			found = true
+		}
+	}
+	return time.Unix(newest, 0).UTC(), found, nil
+}
+
+// assertionError is an assertion about the results that failed to hold.
+type assertionError struct {
+	message string
+}
+
+func (e *assertionError) Error() string {
+	return e.message
+}
+
+// failAssertion reports the failed assertion to stderr and exits unsuccessfully.
+func failAssertion(err error) {
+	fmt.Fprintf(os.Stderr, "assertion failed: %s\n", err.Error())
+	os.Exit(ASSERTION_FAILED_EXIT_CODE)
+}
+
+// checkMaxAge returns an assertion error unless the most recent updated_at of the records in the
+// result is within -max-age, e.g. to alert when an expected update hasn't landed.
+func checkMaxAge(opts *options, endpoint string, result string) error {
+	newest, found, err := newestUpdatedAt(result)
+	if err != nil {
+		handleErr("failed to assert the age of the records", err, INTERNAL_ERROR_EXIT_CODE)
+	}
+	if !found {
+		return &assertionError{fmt.Sprintf("no %s records have an updated_at, select it to assert their age", endpoint)}
+	}
+
+	age := time.Since(newest).Round(time.Second)
+	if age > opts.maxAge {
+		return &assertionError{fmt.Sprintf("the newest %s record was updated at %s, %s ago, expected within %s", endpoint, newest.Format(time.RFC3339), age, opts.maxAge)}
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// Constants for writing batch results to a directory.
+	BATCH_MANIFEST_FILE_NAME = "manifest.json"
+	MAX_SLUG_LENGTH          = 60
+)
+
+// batchEntry is a single endpoint and query read from a batch file.
+type batchEntry struct {
+	line     int
+	endpoint string
+	query    string
+}
+
+// batchResult is the result of a single batch entry in the combined output.
+type batchResult struct {
+	Endpoint string          `json:"endpoint"`
+	Query    string          `json:"query"`
+	Result   json.RawMessage `json:"result,omitempty"`
+
+	// Why the entry failed, in place of its result under -keep-going.
+	Error string `json:"error,omitempty"`
+}
+
+// batchOutcome is the outcome of a single batch entry, written as a line of the -error-log.
+type batchOutcome struct {
+	Line       int    `json:"line"`
+	Endpoint   string `json:"endpoint"`
+	Query      string `json:"query"`
+	Status     string `json:"status"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// readBatch reads the batch file, with one endpoint and query per line separated by whitespace. Blank
+// lines and lines starting with # are ignored.
+func readBatch(opts *options, path string) ([]batchEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []batchEntry{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		i := strings.IndexAny(text, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d must contain an endpoint and query", line)
+		}
+		fields := []string{text[:i], strings.TrimSpace(text[i:])}
+
+		endpoint := resolveEndpoint(fields[0], opts.aliases)
+		err := validateEndpoint(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+		query, err := prepareQuery(opts, endpoint, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+		entries = append(entries, batchEntry{line: line, endpoint: endpoint, query: query})
+	}
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+
+	return entries, nil
+}
+
+// dedupBatch returns the unique entries of the batch, along with the positions at which each occurs.
+// Without deduplication, every entry is unique.
+func dedupBatch(entries []batchEntry, dedup bool) ([]batchEntry, [][]int) {
+	unique := []batchEntry{}
+	occurrences := [][]int{}
+	seen := map[string]int{}
+	for position, entry := range entries {
+		key := entry.endpoint + "\x00" + entry.query
+		if i, ok := seen[key]; ok && dedup {
+			occurrences[i] = append(occurrences[i], position)
+			continue
+		}
+
+		seen[key] = len(unique)
+		unique = append(unique, entry)
+		occurrences = append(occurrences, []int{position})
+	}
+	return unique, occurrences
+}
+
+// effectiveConcurrency clamps the requested concurrency to what the rate limit can sustain, since
+// further workers would only wait on the rate limiter.
+func effectiveConcurrency(opts *options, credentials int) int {
+	concurrency := opts.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if opts.rateLimit <= 0 {
+		return concurrency
+	}
+
+	sustainable := int(math.Ceil(opts.rateLimit * float64(credentials)))
+	if concurrency > sustainable {
+		fmt.Fprintf(os.Stderr, "-concurrency %d exceeds what the rate limit of %g requests per second can sustain, using %d\n", concurrency, opts.rateLimit, sustainable)
+		return sustainable
+	}
+	return concurrency
+}
+
+// runConcurrently runs the function for each index with up to the given number of workers,
+// stopping at the first error.
+func runConcurrently(ctx context.Context, concurrency int, count int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indexes := make(chan int)
+	errs := make(chan error, count)
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				err := fn(ctx, i)
+				if err != nil {
+					errs <- err
+					cancel()
+				}
+			}
+		}()
+	}
+
+	// Stop handing out work once cancelled.
+	for i := 0; i < count && ctx.Err() == nil; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
+
+// runBatch submits every entry of the batch file, printing the combined results in order.
+func runBatch(opts *options, httpClient *http.Client) {
+	if len(opts.args) > 0 {
+		handleErr("failed to read the batch", errors.New("-batch can't be combined with a positional endpoint or query"), BAD_USAGE_EXIT_CODE)
+	}
+	entries, err := readBatch(opts, opts.batch)
+	if err != nil {
+		handleErr("failed to read the batch", err, BAD_USAGE_EXIT_CODE)
+	}
+
+	ctx, cancel := newRunContext(opts)
+	defer cancel()
+	databaseClient := newDatabaseClient(ctx, opts, httpClient)
+
+	// Identical entries are submitted once, with their result reused for every occurrence.
+	unique, occurrences := dedupBatch(entries, !opts.noDedup)
+
+	results := make([]batchResult, len(entries))
+	failures := make([]error, len(entries))
+	concurrency := effectiveConcurrency(opts, len(databaseClient.credentials))
+	err = runConcurrently(ctx, concurrency, len(unique), func(ctx context.Context, i int) error {
+		entry := unique[i]
+		meta := newResultMeta(entry.endpoint, entry.query)
+		result, err := submitQuery(ctx, opts, databaseClient, entry.endpoint, entry.query)
+		if err == nil {
+			meta.rateLimit = databaseClient.LastRateLimitStatus()
+			result, err = processResult(opts, meta, result)
+		}
+
+		// Capture the failure in place of the result when keeping going.
+		if err != nil && opts.keepGoing && ctx.Err() == nil {
+			for _, position := range occurrences[i] {
+				results[position] = batchResult{Endpoint: entry.endpoint, Query: entry.query, Error: describeQueryErr(opts, err).Error()}
+				failures[position] = err
+			}
+			return nil
+		}
+		if err != nil {
+			return &batchError{line: entry.line, err: err}
+		}
+
+		for _, position := range occurrences[i] {
+			results[position] = batchResult{Endpoint: entry.endpoint, Query: entry.query, Result: json.RawMessage(result)}
+		}
+		return nil
+	})
+	if opts.metricsOut != "" {
+		writeMetricsFile(opts.metricsOut, databaseClient)
+	}
+	if err != nil {
+		explainRateLimit(opts, databaseClient)
+		handleQueryErr(opts, "failed to run the batch", err)
+	}
+
+	if opts.errorLog != "" {
+		err = writeErrorLog(opts.errorLog, entries, results, failures)
+		if err != nil {
+			handleErr("failed to write the error log", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+	}
+
+	// Write each result to its own file, or print them combined.
+	if opts.outputDir != "" {
+		err = writeBatchFiles(opts.outputDir, entries, results)
+		if err != nil {
+			handleErr("failed to write the batch results", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+		printfStdout("Wrote %d results and %s to %s\n", len(results), BATCH_MANIFEST_FILE_NAME, opts.outputDir)
+	} else {
+		output, err := encodeOutputJSON(opts, results)
+		if err != nil {
+			handleErr("failed to encode the batch results", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+		printOutput(opts, output)
+	}
+
+	// Entries that failed under -keep-going leave the batch incomplete.
+	for _, failure := range failures {
+		if failure != nil {
+			os.Exit(INCOMPLETE_EXIT_CODE)
+		}
+	}
+}
+
+// writeErrorLog writes the outcome of every entry as JSON lines to the file, so that failures may
+// be triaged apart from the results.
+func writeErrorLog(path string, entries []batchEntry, results []batchResult, failures []error) error {
+	lines := []string{}
+	for i, entry := range entries {
+		outcome := batchOutcome{Line: entry.line, Endpoint: entry.endpoint, Query: entry.query, Status: "ok"}
+		if failures[i] != nil {
+			outcome.Status = "error"
+			outcome.Error = results[i].Error
+			var respErr *ResponseError
+			if errors.As(failures[i], &respErr) {
+				outcome.StatusCode = respErr.StatusCode
+			}
+		}
+		line, err := encodeCompactJSON(outcome)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line+"\n")
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "")), 0644)
+}
+
+// manifestEntry maps a file written by -output-dir to the batch entry it holds the result of, or
+// records why the entry failed under -keep-going.
+type manifestEntry struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line"`
+	Endpoint string `json:"endpoint"`
+	Query    string `json:"query"`
+	Error    string `json:"error,omitempty"`
+}
+
+// slugPattern matches the runs of characters replaced when slugifying names.
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// batchFileName names the result file of the entry by its line along with a slug of its endpoint and query.
+func batchFileName(entry batchEntry) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(entry.endpoint+" "+entry.query), "-"), "-")
+	if len(slug) > MAX_SLUG_LENGTH {
+		slug = strings.TrimRight(slug[:MAX_SLUG_LENGTH], "-")
+	}
+	return fmt.Sprintf("line%d-%s.json", entry.line, slug)
+}
+
+// writeBatchFiles writes each result to its own file within the directory, along with a manifest
+// mapping the files to their entries.
+func writeBatchFiles(dir string, entries []batchEntry, results []batchResult) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	manifest := []manifestEntry{}
+	for i, entry := range entries {
+		// Failed entries have no result to write, only their error in the manifest.
+		if results[i].Error != "" {
+			manifest = append(manifest, manifestEntry{Line: entry.line, Endpoint: entry.endpoint, Query: entry.query, Error: results[i].Error})
+			continue
+		}
+		name := batchFileName(entry)
+		err := os.WriteFile(filepath.Join(dir, name), append([]byte(results[i].Result), '\n'), 0644)
+		if err != nil {
+			return err
+		}
+		manifest = append(manifest, manifestEntry{File: name, Line: entry.line, Endpoint: entry.endpoint, Query: entry.query})
+	}
+
+	manifestJSON, err := encodeJSON(manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, BATCH_MANIFEST_FILE_NAME), []byte(manifestJSON+"\n"), 0644)
+}
+
+// batchError is returned when a batch entry fails, identifying it by its line.
+type batchError struct {
+	line int
+	err  error
+}
+
+// Error describes the failed line along with its error.
+func (e *batchError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.err.Error())
+}
+
+// Unwrap returns the error of the failed entry.
+func (e *batchError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// surfaceBrokenPipes has writes to a closed stdout fail with EPIPE, for exitOnBrokenPipe to exit
+// on, where Go otherwise kills the program with SIGPIPE. Receiving SIGPIPE is all that's needed,
+// so the signals themselves are discarded.
+func surfaceBrokenPipes() {
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGPIPE)
+}
+
+// writeStdout writes the text to stdout, exiting quietly when stdout was closed early.
+func writeStdout(text string) {
+	_, err := io.WriteString(os.Stdout, text)
+	exitOnBrokenPipe(err)
+}
+
+// printfStdout formats the text like fmt.Printf and writes it to stdout with writeStdout.
+func printfStdout(format string, args ...interface{}) {
+	writeStdout(fmt.Sprintf(format, args...))
+}
+
+// stdoutWriter writes to stdout, exiting quietly when stdout was closed early, e.g. for the usage
+// printed by the flags.
+type stdoutWriter struct{}
+
+func (stdoutWriter) Write(p []byte) (int, error) {
+	n, err := os.Stdout.Write(p)
+	exitOnBrokenPipe(err)
+	return n, err
+}
+
+// exitOnBrokenPipe exits quietly when the error is due to stdout being closed early, e.g. by head
+// at the end of a pipeline, since there's no one left to report it to.
+func exitOnBrokenPipe(err error) {
+	if errors.Is(err, syscall.EPIPE) {
+		os.Exit(BROKEN_PIPE_EXIT_CODE)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+// Set in the environment of the test binary re-run to write to a closed stdout, to surface broken
+// pipes or to leave SIGPIPE to Go's default handling.
+const BROKEN_PIPE_HELPER_ENV_VAR = "GAMERS_CONSOLE_BROKEN_PIPE_HELPER"
+
+func TestBrokenPipeHelper(t *testing.T) {
+	mode := os.Getenv(BROKEN_PIPE_HELPER_ENV_VAR)
+	if mode == "" {
+		t.Skip("only run as the helper of TestPrintfStdoutOnBrokenPipe")
+	}
+	if mode == "surfaced" {
+		surfaceBrokenPipes()
+	}
+	for {
+		printfStdout("%s\n", "a line of output")
+	}
+}
+
+// runBrokenPipeHelper runs the helper in the mode with its stdout closed, returning how it exited.
+func runBrokenPipeHelper(t *testing.T, mode string) *exec.ExitError {
+	t.Helper()
+	read, write, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	read.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestBrokenPipeHelper$")
+	cmd.Env = append(os.Environ(), BROKEN_PIPE_HELPER_ENV_VAR+"="+mode)
+	cmd.Stdout = write
+	err = cmd.Run()
+	write.Close()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected the helper to exit unsuccessfully, got %v", err)
+	}
+	return exitErr
+}
+
+func TestPrintfStdoutOnBrokenPipe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires SIGPIPE")
+	}
+
+	// Go's default handling of SIGPIPE kills the program before the write returns.
+	exitErr := runBrokenPipeHelper(t, "default")
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() || status.Signal() != syscall.SIGPIPE {
+		t.Errorf("expected the helper to be killed by SIGPIPE by default, got %s", exitErr)
+	}
+
+	// Surfacing broken pipes exits with the defined exit code instead.
+	exitErr = runBrokenPipeHelper(t, "surfaced")
+	if exitErr.ExitCode() != BROKEN_PIPE_EXIT_CODE {
+		t.Errorf("expected exit code %d, got %d", BROKEN_PIPE_EXIT_CODE, exitErr.ExitCode())
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// Name of the file caching the fields observed in responses, within the cache directory.
+	FIELDS_CACHE_FILE_NAME = "fields.json"
+)
+
+// cacheDir returns the directory of the program's caches within the user's cache directory.
+func cacheDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userCacheDir, CONFIG_DIR_NAME), nil
+}
+
+// loadObservedFields reads the fields observed per endpoint, which is empty when nothing is cached yet.
+func loadObservedFields() (map[string][]string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	observed := map[string][]string{}
+	cached, err := os.ReadFile(filepath.Join(dir, FIELDS_CACHE_FILE_NAME))
+	if errors.Is(err, fs.ErrNotExist) {
+		return observed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(cached, &observed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fields cache: %s", err.Error())
+	}
+	return observed, nil
+}
+
+// isWildcardQuery reports whether the query selects every field, i.e. fields *.
+func isWildcardQuery(query string) bool {
+	q, err := parseQuery(query)
+	if err != nil {
+		return false
+	}
+	fields, ok := q.get("fields")
+	return ok && strings.TrimSpace(fields) == "*"
+}
+
+// recordObservedFields adds the top level fields of the records in the result to those cached
+// for the endpoint.
+func recordObservedFields(endpoint string, result string) error {
+	records, err := decodeRecords(result)
+	if err != nil {
+		return err
+	}
+	observed, err := loadObservedFields()
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, field := range observed[endpoint] {
+		seen[field] = true
+	}
+	for _, record := range records {
+		for field := range record {
+			seen[field] = true
+		}
+	}
+	fields := []string{}
+	for field := range seen {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	observed[endpoint] = fields
+
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	cached, err := encodeJSON(observed)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, FIELDS_CACHE_FILE_NAME), []byte(cached+"\n"), 0644)
+}
+
+// suggestFields returns the fields observed for the endpoint by earlier fields * queries.
+func suggestFields(endpoint string) ([]string, error) {
+	observed, err := loadObservedFields()
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := observed[endpoint]
+	if !ok {
+		return nil, fmt.Errorf("no fields observed for %s yet, run a query with fields *; to observe them", endpoint)
+	}
+	return fields, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	// Set to true by most CI services, see e.g. GitHub Actions and GitLab CI.
+	CI_ENV_VAR = "CI"
+
+	// The supported formats of errors.
+	ERROR_FORMAT_TEXT = "text"
+	ERROR_FORMAT_JSON = "json"
+)
+
+// errorFormat is the format errors are printed to stderr in, set from -error-format.
+var errorFormat = ERROR_FORMAT_TEXT
+
+// errorReport is an error printed as JSON with -error-format json.
+type errorReport struct {
+	Message  string `json:"message"`
+	Error    string `json:"error"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// validateErrorFormat validates that the error format is supported.
+func validateErrorFormat(format string) error {
+	switch format {
+	case ERROR_FORMAT_TEXT, ERROR_FORMAT_JSON:
+		return nil
+	default:
+		return fmt.Errorf("unsupported error format %q, expected one of %s or %s", format, ERROR_FORMAT_TEXT, ERROR_FORMAT_JSON)
+	}
+}
+
+// isCI reports whether the program runs under CI, either forced with -ci or detected from CI.
+func isCI(opts *options) bool {
+	if opts.ci {
+		return true
+	}
+	ci, _ := strconv.ParseBool(os.Getenv(CI_ENV_VAR))
+	return ci
+}
+
+// applyCIDefaults quietens the output under CI, disabling color, the banner and the pager and
+// printing errors as JSON, except where overridden by flags, including those of the environment.
+// It's applied after the config file, so that the config file can't undo it.
+func applyCIDefaults(opts *options) {
+	if !isCI(opts) {
+		return
+	}
+	if !isFlagSet("color") {
+		opts.color = COLOR_NEVER
+	}
+	if !isFlagSet("banner") {
+		opts.banner = ""
+	}
+	if !isFlagSet("pager") {
+		opts.noPager = true
+	}
+	if !isFlagSet("error-format") {
+		opts.errorFormat = ERROR_FORMAT_JSON
+	}
+}
+
+// printErr prints the error to stderr in the error format.
+func printErr(message string, err error, exitCode int) {
+	if errorFormat == ERROR_FORMAT_JSON {
+		report, jsonErr := json.Marshal(&errorReport{Message: message, Error: err.Error(), ExitCode: exitCode})
+		if jsonErr == nil {
+			fmt.Fprintf(os.Stderr, "%s\n", report)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%s with error: %s\n", message, err.Error())
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestCIDefaultsOverrideTheConfigFile(t *testing.T) {
+	configured := "Configured banner"
+	cfg := &config{Banner: &configured}
+	cases := []struct {
+		name        string
+		args        []string
+		defaultArgs string
+		banner      string
+	}{
+		{"config file", []string{"-ci"}, "", ""},
+		{"flag", []string{"-ci", "-banner", "Flag banner"}, "", "Flag banner"},
+		{"default flags", []string{"-ci"}, "-banner Env", "Env"},
+		{"outside CI", nil, "", configured},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv(CI_ENV_VAR, "")
+			flags = newFlagSet()
+			t.Setenv(DEFAULT_OPTIONS_ENV_VAR, c.defaultArgs)
+			opts, err := parseOptions(c.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			applyConfig(opts, cfg)
+			applyCIDefaults(opts)
+			if opts.banner != c.banner {
+				t.Errorf("expected the banner %q, got %q", c.banner, opts.banner)
+			}
+		})
+	}
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Constants for interacting with the IGDB developer API.
+	IGDB_BASE_URL          = "https://api.igdb.com/v4"
+	IGDB_CLIENT_ID_HEADER  = "Client-ID"
+	IGDB_AUTH_TOKEN_HEADER = "Authorization"
+	IGDB_ACCEPT_HEADER     = "Accept"
+	DEFAULT_IGDB_ACCEPT    = "application/json"
+
+	// Queries are sent as plain text, however large.
+	IGDB_CONTENT_TYPE_HEADER = "Content-Type"
+	IGDB_QUERY_CONTENT_TYPE  = "text/plain; charset=utf-8"
+
+	// The most of an unread response body drained to reuse its connection.
+	MAX_DRAINED_BODY_SIZE = 1 << 20
+
+	// The IGDB allows up to 4 requests per second per client ID.
+	DEFAULT_IGDB_RATE_LIMIT = 4
+)
+
+// ErrMissingClientID is returned when querying without a client ID, which the IGDB requires.
+var ErrMissingClientID = errors.New("a client ID is required to query the IGDB")
+
+// ResponseError is returned when the IGDB responds with a non-successful status.
+type ResponseError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+// Error returns the status and body of the unsuccessful response.
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("unexpected status %s: %s", e.Status, e.Body)
+}
+
+// igdbError represents a single error within the structured error body returned by the IGDB.
+type igdbError struct {
+	Title   string `json:"title"`
+	Status  int    `json:"status"`
+	Cause   string `json:"cause"`
+	Details string `json:"details"`
+}
+
+// igdbMessage represents the message error body returned by the IGDB, e.g. for failed authorization.
+type igdbMessage struct {
+	Message string `json:"message"`
+}
+
+// Pretty returns the status and a summary parsed from the structured error body, falling back to
+// the raw body when it isn't structured.
+func (e *ResponseError) Pretty() string {
+	errs := []igdbError{}
+	err := json.Unmarshal([]byte(e.Body), &errs)
+	if err == nil && len(errs) > 0 {
+		summaries := []string{}
+		for _, igdbErr := range errs {
+			summary := igdbErr.Title
+			if igdbErr.Cause != "" {
+				summary = fmt.Sprintf("%s: %s", summary, igdbErr.Cause)
+			}
+			if igdbErr.Details != "" {
+				summary = fmt.Sprintf("%s (%s)", summary, igdbErr.Details)
+			}
+			summaries = append(summaries, summary)
+		}
+		return fmt.Sprintf("unexpected status %s: %s", e.Status, strings.Join(summaries, "; "))
+	}
+
+	message := &igdbMessage{}
+	err = json.Unmarshal([]byte(e.Body), message)
+	if err == nil && message.Message != "" {
+		return fmt.Sprintf("unexpected status %s: %s", e.Status, message.Message)
+	}
+
+	return e.Error()
+}
+
+// clientCredentials are the credentials used to authorize requests, along with their rate limiter.
+type clientCredentials struct {
+	clientID  string
+	authToken string
+
+	// Limits the rate of requests, or nil for unlimited.
+	limiter *rateLimiter
+}
+
+// DatabaseClient is a client for interacting with the IGDB.
+type DatabaseClient struct {
+	httpClient *http.Client
+	accept     string
+	logger     *log.Logger
+
+	// Where the network timings of each request are reported, or nil for nowhere.
+	trace io.Writer
+
+	// The maximum size of a response body in bytes, or zero for unlimited.
+	maxResponseSize int64
+
+	// The credentials used in turn for each request, each rate limited independently.
+	credentials     []*clientCredentials
+	nextCredentials uint32
+	rateLimit       float64
+
+	// The retrying of throttled and failed responses, with jittered backoff.
+	retries    int
+	retryDelay time.Duration
+	jitter     *retryJitter
+
+	// Counts the requests made, by outcome and latency.
+	metrics *requestMetrics
+
+	// The retries allowed across every request.
+	retryBudget *retryBudget
+
+	// The number of responses throttled with 429 Too Many Requests.
+	throttled uint32
+
+	// The rate limit status reported by the latest response, if any.
+	rateLimitMu     sync.Mutex
+	rateLimitStatus *RateLimitStatus
+}
+
+// NewDatabaseClient instantiates a new instance of the database client.
+func NewDatabaseClient(clientID string, authToken string) *DatabaseClient {
+	d := &DatabaseClient{
+		httpClient:  http.DefaultClient,
+		accept:      DEFAULT_IGDB_ACCEPT,
+		logger:      log.New(io.Discard, "", 0),
+		rateLimit:   DEFAULT_IGDB_RATE_LIMIT,
+		retryDelay:  DEFAULT_RETRY_DELAY,
+		jitter:      newRetryJitter(),
+		metrics:     newRequestMetrics(),
+		retryBudget: &retryBudget{},
+	}
+	d.AddCredentials(clientID, authToken)
+	return d
+}
+
+// AddCredentials adds another client ID and auth token, which are used in turn with the existing
+// credentials to spread requests across them.
+func (d *DatabaseClient) AddCredentials(clientID string, authToken string) {
+	creds := &clientCredentials{
+		clientID:  clientID,
+		authToken: authToken,
+	}
+	if d.rateLimit > 0 {
+		creds.limiter = newRateLimiter(d.rateLimit)
+	}
+	d.credentials = append(d.credentials, creds)
+}
+
+// SetHTTPClient sets the HTTP client used to make requests.
+func (d *DatabaseClient) SetHTTPClient(httpClient *http.Client) {
+	d.httpClient = httpClient
+}
+
+// SetAccept overrides the Accept header sent with each request.
+func (d *DatabaseClient) SetAccept(accept string) {
+	d.accept = accept
+}
+
+// SetLogger sets the logger used to report the details of each request.
+func (d *DatabaseClient) SetLogger(logger *log.Logger) {
+	d.logger = logger
+}
+
+// SetTrace reports the DNS, connect, TLS and first byte timings of each request to the writer,
+// where nil disables tracing.
+func (d *DatabaseClient) SetTrace(w io.Writer) {
+	d.trace = w
+}
+
+// SetMaxResponseSize limits the size of response bodies in bytes, where zero is unlimited.
+func (d *DatabaseClient) SetMaxResponseSize(maxResponseSize int64) {
+	d.maxResponseSize = maxResponseSize
+}
+
+// SetRetries sets how many times throttled and failed responses are retried, and the base delay
+// before retrying, which doubles with each retry.
+func (d *DatabaseClient) SetRetries(retries int, delay time.Duration) {
+	d.retries = retries
+	d.retryDelay = delay
+}
+
+// SetRetryBudget caps the retries across every request made with the client, after which failed
+// responses are returned without retrying, where zero is unlimited.
+func (d *DatabaseClient) SetRetryBudget(budget int) {
+	d.retryBudget = &retryBudget{limit: budget}
+}
+
+// shareRetryBudget draws the retries of the client from the budget, e.g. one already drawn on
+// while authenticating.
+func (d *DatabaseClient) shareRetryBudget(budget *retryBudget) {
+	d.retryBudget = budget
+}
+
+// takeRetry takes a retry from the retry budget, reporting whether one remained.
+func (d *DatabaseClient) takeRetry() bool {
+	return d.retryBudget.take(d.logger)
+}
+
+// SetRateLimit limits the requests made per second for each credentials, where zero is unlimited.
+func (d *DatabaseClient) SetRateLimit(requestsPerSecond float64) {
+	d.rateLimit = requestsPerSecond
+	for _, creds := range d.credentials {
+		creds.limiter = nil
+		if requestsPerSecond > 0 {
+			creds.limiter = newRateLimiter(requestsPerSecond)
+		}
+	}
+}
+
+// LastRateLimitStatus returns the rate limit status reported by the latest response, or nil when
+// no response has reported one.
+func (d *DatabaseClient) LastRateLimitStatus() *RateLimitStatus {
+	d.rateLimitMu.Lock()
+	defer d.rateLimitMu.Unlock()
+	return d.rateLimitStatus
+}
+
+// ThrottledCount returns the number of responses throttled with 429 Too Many Requests.
+func (d *DatabaseClient) ThrottledCount() int {
+	return int(atomic.LoadUint32(&d.throttled))
+}
+
+// recordRateLimitStatus records and logs the rate limit status reported by the response, if any.
+func (d *DatabaseClient) recordRateLimitStatus(resp *http.Response) {
+	status := parseRateLimitStatus(resp.Header)
+	if status == nil {
+		return
+	}
+	d.logger.Printf("Rate limit: %s", status.String())
+
+	d.rateLimitMu.Lock()
+	defer d.rateLimitMu.Unlock()
+	d.rateLimitStatus = status
+}
+
+// selectCredentials selects the credentials for the next request in round-robin order.
+func (d *DatabaseClient) selectCredentials() *clientCredentials {
+	next := atomic.AddUint32(&d.nextCredentials, 1) - 1
+	return d.credentials[next%uint32(len(d.credentials))]
+}
+
+// newRequest instantiates a new request with the necessary headers. The body is sent with its
+// length rather than chunked, since it's known up front, and can be re-read when the request is
+// retried or redirected, however large the query, e.g. one selecting thousands of IDs.
+func (d *DatabaseClient) newRequest(ctx context.Context, creds *clientCredentials, endpoint string, query string) (*http.Request, error) {
+	reqBody := bytes.NewReader([]byte(query))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s", IGDB_BASE_URL, endpoint), reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add(IGDB_CLIENT_ID_HEADER, creds.clientID)
+	req.Header.Add(IGDB_AUTH_TOKEN_HEADER, fmt.Sprintf("Bearer %s", creds.authToken))
+	req.Header.Add(IGDB_ACCEPT_HEADER, d.accept)
+	req.Header.Add(IGDB_CONTENT_TYPE_HEADER, IGDB_QUERY_CONTENT_TYPE)
+
+	d.logger.Printf("%s %s", req.Method, req.URL.String())
+	d.logger.Printf("%s: %s", IGDB_CLIENT_ID_HEADER, creds.clientID)
+	d.logger.Printf("%s: Bearer <redacted>", IGDB_AUTH_TOKEN_HEADER)
+	d.logger.Printf("%s: %s", IGDB_ACCEPT_HEADER, d.accept)
+	d.logger.Printf("%s: %s", IGDB_CONTENT_TYPE_HEADER, IGDB_QUERY_CONTENT_TYPE)
+	d.logger.Printf("Content-Length: %d", req.ContentLength)
+	d.logger.Printf("Request hash: %s", requestHash(endpoint, query))
+	return req, nil
+}
+
+// parseResponse parses the response body into a JSON string.
+func (d *DatabaseClient) parseResponse(resp *http.Response) (string, error) {
+	// Read one byte past the limit to detect responses exceeding it.
+	var body io.Reader = resp.Body
+	if d.maxResponseSize > 0 {
+		body = io.LimitReader(resp.Body, d.maxResponseSize+1)
+	}
+
+	respBody, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	if d.maxResponseSize > 0 && int64(len(respBody)) > d.maxResponseSize {
+		return "", fmt.Errorf("response exceeded the maximum size of %d bytes", d.maxResponseSize)
+	}
+
+	return string(respBody), nil
+}
+
+// closeBody drains and closes the response body, so that the connection may be reused for later
+// requests even when the body wasn't read in full, e.g. because it exceeded the maximum size.
+// Bodies too large to drain cheaply are abandoned along with their connection.
+func closeBody(resp *http.Response) {
+	io.CopyN(io.Discard, resp.Body, MAX_DRAINED_BODY_SIZE)
+	resp.Body.Close()
+}
+
+// Query queries the client database and returns the parsed JSON response.
+func (d *DatabaseClient) Query(endpoint string, query string) (string, error) {
+	return d.QueryContext(context.Background(), endpoint, query)
+}
+
+// QueryContext queries the client database within the given context and returns the parsed JSON response.
+func (d *DatabaseClient) QueryContext(ctx context.Context, endpoint string, query string) (string, error) {
+	return d.query(ctx, nil, endpoint, query)
+}
+
+// QueryWithCredentials queries the client database within the given context using the given client
+// ID and auth token in place of the client's own, e.g. to serve several tenants with one client.
+// Requests with these credentials aren't rate limited by the client.
+func (d *DatabaseClient) QueryWithCredentials(ctx context.Context, clientID string, authToken string, endpoint string, query string) (string, error) {
+	creds := &clientCredentials{
+		clientID:  clientID,
+		authToken: authToken,
+	}
+	return d.query(ctx, creds, endpoint, query)
+}
+
+// query queries the client database with the credentials, or the next of the client's own when nil,
+// and returns the parsed JSON response.
+func (d *DatabaseClient) query(ctx context.Context, creds *clientCredentials, endpoint string, query string) (string, error) {
+	resp, err := d.do(ctx, creds, endpoint, query)
+	if err != nil {
+		return "", err
+	}
+	defer closeBody(resp)
+
+	parsedResp, err := d.parseResponse(resp)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse response: %s", err.Error())
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", &ResponseError{StatusCode: resp.StatusCode, Status: resp.Status, Body: parsedResp}
+	}
+
+	return parsedResp, nil
+}
+
+// QueryStream queries the client database within the given context, decoding the records of the
+// response one at a time and passing each to the function as it arrives, so that huge responses
+// needn't be held in memory at once.
+func (d *DatabaseClient) QueryStream(ctx context.Context, endpoint string, query string, fn func(record json.RawMessage) error) error {
+	resp, err := d.do(ctx, nil, endpoint, query)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		parsedResp, err := d.parseResponse(resp)
+		if err != nil {
+			return fmt.Errorf("failed to parse response: %s", err.Error())
+		}
+		return &ResponseError{StatusCode: resp.StatusCode, Status: resp.Status, Body: parsedResp}
+	}
+
+	var body io.Reader = resp.Body
+	if d.maxResponseSize > 0 {
+		body = &maxSizeReader{reader: resp.Body, limit: d.maxResponseSize}
+	}
+	decoder := json.NewDecoder(body)
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to parse response: %s", err.Error())
+	}
+	if token != json.Delim('[') {
+		return errors.New("failed to parse response: expected an array of records")
+	}
+	for decoder.More() {
+		record := json.RawMessage{}
+		err = decoder.Decode(&record)
+		if err != nil {
+			return fmt.Errorf("failed to parse response: %s", err.Error())
+		}
+		err = fn(record)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to parse response: %s", err.Error())
+	}
+	return nil
+}
+
+// maxSizeReader reads from the reader, failing once more than the limit of bytes were read.
+type maxSizeReader struct {
+	reader io.Reader
+	limit  int64
+	read   int64
+}
+
+// Read reads from the underlying reader, failing once the limit is exceeded.
+func (r *maxSizeReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return 0, fmt.Errorf("response exceeded the maximum size of %d bytes", r.limit)
+	}
+	return n, err
+}
+
+// do sends the query with the credentials, or the client's own when nil, retrying throttled and
+// failed responses after a jittered backoff while the retry budget allows. The caller must close
+// the response body.
+func (d *DatabaseClient) do(ctx context.Context, creds *clientCredentials, endpoint string, query string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := d.send(ctx, creds, endpoint, query)
+		if err != nil || attempt >= d.retries || !isRetryableStatus(resp.StatusCode) || !d.takeRetry() {
+			return resp, err
+		}
+
+		d.metrics.observeRetry()
+		delay := d.jitter.backoff(resp, d.retryDelay, attempt)
+		closeBody(resp)
+		d.logger.Printf("Retrying %s after %s", resp.Status, delay)
+		err = sleepContext(ctx, delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send sends the query with the credentials, or the next of the client's own when nil, once the
+// rate limit allows, recording the rate limit status of the response. The caller must close the
+// response body.
+func (d *DatabaseClient) send(ctx context.Context, creds *clientCredentials, endpoint string, query string) (*http.Response, error) {
+	if creds == nil {
+		creds = d.selectCredentials()
+	}
+	if creds.clientID == "" {
+		return nil, ErrMissingClientID
+	}
+	if creds.limiter != nil {
+		err := creds.limiter.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var trace *requestTrace
+	if d.trace != nil {
+		trace = newRequestTrace()
+		ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+	}
+
+	req, err := d.newRequest(ctx, creds, endpoint, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %s", err.Error())
+	}
+
+	start := time.Now()
+	resp, err := d.httpClient.Do(req)
+	statusCode := 0
+	if err == nil {
+		statusCode = resp.StatusCode
+	}
+	d.metrics.observe(statusCode, time.Since(start))
+	if trace != nil {
+		fmt.Fprintf(d.trace, "Trace %s %s: %s\n", req.Method, req.URL.String(), trace.String())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %s", err.Error())
+	}
+	d.recordRateLimitStatus(resp)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		atomic.AddUint32(&d.throttled, 1)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// rewriteTransport sends every request to the test server in place of the IGDB.
+type rewriteTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return t.next.RoundTrip(req)
+}
+
+// parseTestOptions parses the arguments into options with a fresh set of flags.
+func parseTestOptions(t *testing.T, args ...string) *options {
+	t.Helper()
+	flags = newFlagSet()
+	t.Setenv(DEFAULT_OPTIONS_ENV_VAR, "")
+	opts, err := parseOptions(args)
+	if err != nil {
+		t.Fatalf("failed to parse options %q: %s", args, err.Error())
+	}
+	return opts
+}
+
+// newTestClient instantiates a database client configured by the options, sending its requests
+// to a test server serving the handler.
+func newTestClient(t *testing.T, opts *options, handler http.Handler) (*DatabaseClient, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	httpClient, err := newHTTPClient(opts)
+	if err != nil {
+		t.Fatalf("failed to instantiate the HTTP client: %s", err.Error())
+	}
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient.Transport = &rewriteTransport{target: target, next: httpClient.Transport}
+
+	databaseClient := NewDatabaseClient("client-id", "auth-token")
+	databaseClient.SetHTTPClient(httpClient)
+	databaseClient.SetRateLimit(0)
+	return databaseClient, server
+}
+
+func TestQueryReusesConnections(t *testing.T) {
+	databaseClient, _ := newTestClient(t, parseTestOptions(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"id":1}]`)
+	}))
+
+	reused := 0
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				reused++
+			}
+		},
+	})
+	const queries = 5
+	for i := 0; i < queries; i++ {
+		_, err := databaseClient.QueryContext(ctx, "games", "fields name;")
+		if err != nil {
+			t.Fatalf("query %d failed: %s", i, err.Error())
+		}
+	}
+
+	if reused != queries-1 {
+		t.Errorf("expected every query after the first to reuse its connection, %d of %d did", reused, queries-1)
+	}
+}
+
+func TestQuerySendsLargeBodiesIntact(t *testing.T) {
+	query := "fields name; where id = (" + strings.Repeat("123456,", 1000) + "1);"
+
+	var body, contentType, contentLength string
+	databaseClient, _ := newTestClient(t, parseTestOptions(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read the request body: %s", err.Error())
+		}
+		body = string(received)
+		contentType = r.Header.Get(IGDB_CONTENT_TYPE_HEADER)
+		contentLength = r.Header.Get("Content-Length")
+		io.WriteString(w, `[]`)
+	}))
+
+	_, err := databaseClient.QueryContext(context.Background(), "games", query)
+	if err != nil {
+		t.Fatalf("query failed: %s", err.Error())
+	}
+	if body != query {
+		t.Errorf("expected the server to receive the %d byte query intact, received %d bytes", len(query), len(body))
+	}
+	if contentType != IGDB_QUERY_CONTENT_TYPE {
+		t.Errorf("expected Content-Type %q, got %q", IGDB_QUERY_CONTENT_TYPE, contentType)
+	}
+	if contentLength != strconv.Itoa(len(query)) {
+		t.Errorf("expected Content-Length %d, got %s", len(query), contentLength)
+	}
+}
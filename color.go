@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// The modes of -color.
+	COLOR_AUTO   = "auto"
+	COLOR_ALWAYS = "always"
+	COLOR_NEVER  = "never"
+
+	// Disables color when set to any value, see https://no-color.org.
+	NO_COLOR_ENV_VAR = "NO_COLOR"
+
+	// ANSI escape sequences styling the output.
+	ANSI_BOLD  = "\033[1m"
+	ANSI_RED   = "\033[31m"
+	ANSI_GREEN = "\033[32m"
+	ANSI_RESET = "\033[0m"
+)
+
+// validateColor validates that the color mode is supported.
+func validateColor(color string) error {
+	switch color {
+	case COLOR_AUTO, COLOR_ALWAYS, COLOR_NEVER:
+		return nil
+	default:
+		return fmt.Errorf("unsupported color %q, expected one of %s, %s or %s", color, COLOR_AUTO, COLOR_ALWAYS, COLOR_NEVER)
+	}
+}
+
+// useColor reports whether stdout should be colored. By default color is used only when stdout is
+// a terminal and NO_COLOR isn't set.
+func useColor(opts *options) bool {
+	switch opts.color {
+	case COLOR_ALWAYS:
+		return true
+	case COLOR_NEVER:
+		return false
+	}
+	if _, ok := os.LookupEnv(NO_COLOR_ENV_VAR); ok {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
+
+// isTerminal reports whether the file is a terminal rather than a pipe or regular file.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize styles the text with the ANSI escape sequence when color is in use.
+func colorize(opts *options, style string, text string) string {
+	if !useColor(opts) {
+		return text
+	}
+	return style + text + ANSI_RESET
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// The fields of the game and the companies involved in it.
+	GAME_NAME_FIELDS        = "name"
+	INVOLVED_COMPANY_FIELDS = "company.name,developer,publisher"
+
+	// The most companies involved in a game fetched at once, the IGDB's maximum limit.
+	MAX_INVOLVED_COMPANIES = 500
+)
+
+// Company is a company involved in a game, named by the IGDB companies endpoint.
+type Company struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// GameCompanies is a game along with the companies that developed and published it.
+type GameCompanies struct {
+	Game       Company   `json:"game"`
+	Developers []Company `json:"developers"`
+	Publishers []Company `json:"publishers"`
+}
+
+// involvedCompany is a record of the IGDB involved_companies endpoint, with its company expanded.
+type involvedCompany struct {
+	Company   Company `json:"company"`
+	Developer bool    `json:"developer"`
+	Publisher bool    `json:"publisher"`
+}
+
+// QueryGameCompanies queries the game and the companies involved in it, resolving their names and
+// grouping them by their roles as developers and publishers. A company may fill both roles.
+func (d *DatabaseClient) QueryGameCompanies(ctx context.Context, gameID int64) (*GameCompanies, error) {
+	games := []Company{}
+	err := d.queryInto(ctx, "games", fmt.Sprintf("where id = %d;", gameID), GAME_NAME_FIELDS, &games)
+	if err != nil {
+		return nil, err
+	}
+	if len(games) == 0 {
+		return nil, fmt.Errorf("no game has the id %d", gameID)
+	}
+
+	query := fmt.Sprintf("where game = %d; limit %d;", gameID, MAX_INVOLVED_COMPANIES)
+	involved := []involvedCompany{}
+	err = d.queryInto(ctx, "involved_companies", query, INVOLVED_COMPANY_FIELDS, &involved)
+	if err != nil {
+		return nil, err
+	}
+
+	companies := &GameCompanies{
+		Game:       games[0],
+		Developers: []Company{},
+		Publishers: []Company{},
+	}
+	for _, involvement := range involved {
+		if involvement.Developer {
+			companies.Developers = append(companies.Developers, involvement.Company)
+		}
+		if involvement.Publisher {
+			companies.Publishers = append(companies.Publishers, involvement.Company)
+		}
+	}
+	return companies, nil
+}
+
+// runGameCompanies prints the developers and publishers of the game with the given id.
+func runGameCompanies(opts *options, httpClient *http.Client) {
+	if len(opts.args) > 0 {
+		handleErr("failed to fetch the game's companies", errors.New("-game-companies can't be combined with a positional endpoint or query"), BAD_USAGE_EXIT_CODE)
+	}
+	gameID, err := strconv.ParseInt(opts.gameCompanies, 10, 64)
+	if err != nil || gameID <= 0 {
+		handleErr("failed to fetch the game's companies", fmt.Errorf("invalid game id %q", opts.gameCompanies), BAD_USAGE_EXIT_CODE)
+	}
+
+	ctx, cancel := newRunContext(opts)
+	defer cancel()
+	databaseClient := newDatabaseClient(ctx, opts, httpClient)
+
+	companies, err := databaseClient.QueryGameCompanies(ctx, gameID)
+	if err != nil {
+		explainRateLimit(opts, databaseClient)
+		handleQueryErr(opts, "failed to fetch the game's companies", err)
+	}
+
+	output, err := encodeOutputJSON(opts, companies)
+	if err != nil {
+		handleErr("failed to encode the game's companies", err, INTERNAL_ERROR_EXIT_CODE)
+	}
+	printOutput(opts, output)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// completionTemplates are the completion scripts for each supported shell, formatted with
+// the space separated flag names and endpoints.
+var completionTemplates = map[string]string{
+	"bash": `_gamers_console() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=( $(compgen -W "%s" -- "$cur") )
+    else
+        COMPREPLY=( $(compgen -W "%s" -- "$cur") )
+    fi
+}
+complete -F _gamers_console gamers-console
+`,
+	"zsh": `#compdef gamers-console
+_gamers_console() {
+    if [[ "$PREFIX" == -* ]]; then
+        compadd -- %s
+    else
+        compadd -- %s
+    fi
+}
+compdef _gamers_console gamers-console
+`,
+	"fish": `for flag in %s
+    complete -c gamers-console -o (string trim -l -c - -- $flag)
+end
+complete -c gamers-console -f -n 'not __fish_seen_subcommand_from %[2]s' -a '%[2]s'
+`,
+}
+
+// completionScript generates the completion script for the given shell.
+func completionScript(shell string) (string, error) {
+	template, ok := completionTemplates[shell]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell %q, expected one of bash, zsh or fish", shell)
+	}
+
+	flagNames := []string{}
+	flags.VisitAll(func(f *flag.Flag) {
+		flagNames = append(flagNames, "-"+f.Name)
+	})
+
+	return fmt.Sprintf(template, strings.Join(flagNames, " "), strings.Join(knownEndpoints, " ")), nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// Constants for locating the config file.
+	CONFIG_DIR_NAME  = "gamers-console"
+	CONFIG_FILE_NAME = "config.json"
+)
+
+// config holds the user's persistent settings, read from the config file. Unset settings
+// are nil so that the flag defaults apply.
+type config struct {
+	Banner  *string           `json:"banner"`
+	Aliases map[string]string `json:"aliases"`
+	Macros  map[string]string `json:"macros"`
+}
+
+// defaultConfigPath returns the path of the config file within the user's config directory.
+func defaultConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, CONFIG_DIR_NAME, CONFIG_FILE_NAME), nil
+}
+
+// loadConfig reads the config file at the given path, or the default path when empty. A missing
+// default config file is treated as an empty config.
+func loadConfig(path string) (*config, error) {
+	explicit := path != ""
+	if !explicit {
+		defaultPath, err := defaultConfigPath()
+		if err != nil {
+			return &config{}, nil
+		}
+		path = defaultPath
+	}
+
+	configBytes, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config{}
+	err = json.Unmarshal(configBytes, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %s", path, err.Error())
+	}
+
+	return cfg, nil
+}
+
+// isFlagSet reports whether the flag was explicitly given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// applyConfig applies the config settings to the options, except where overridden by flags.
+func applyConfig(opts *options, cfg *config) {
+	if cfg.Banner != nil && !isFlagSet("banner") {
+		opts.banner = *cfg.Banner
+	}
+
+	// The configured aliases extend, and take precedence over, the defaults.
+	opts.aliases = map[string]string{}
+	for alias, endpoint := range defaultEndpointAliases {
+		opts.aliases[alias] = endpoint
+	}
+	for alias, endpoint := range cfg.Aliases {
+		opts.aliases[alias] = endpoint
+	}
+	opts.macros = cfg.Macros
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// Pulls needing more requests than this are confirmed before they're fetched.
+	CONFIRM_REQUEST_THRESHOLD = 10
+)
+
+// confirmPull asks for confirmation before paging through a large number of records, estimated
+// by counting them first, unless -yes is set or stdin isn't a terminal that could answer.
+func confirmPull(ctx context.Context, opts *options, databaseClient *DatabaseClient, endpoint string, query string) {
+	if opts.yes || !(opts.all || opts.since != "") || opts.idsFile != "" || !isTerminal(os.Stdin) {
+		return
+	}
+	q, offset, err := parsePagedQuery(query)
+	if err != nil {
+		return
+	}
+	count, err := countRecords(ctx, databaseClient, endpoint, q)
+	if err != nil {
+		databaseClient.logger.Printf("Failed to count the records, skipping confirmation: %s", err.Error())
+		return
+	}
+
+	records := count - offset
+	requests := (records + MAX_QUERY_LIMIT - 1) / MAX_QUERY_LIMIT
+	if requests <= CONFIRM_REQUEST_THRESHOLD {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "This may fetch %d records across %d requests, continue? [y/N] ", records, requests)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return
+	default:
+		handleErr("aborted the query", errors.New("the pull wasn't confirmed, use -yes to skip confirmation"), BAD_USAGE_EXIT_CODE)
+	}
+}
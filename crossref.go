@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// The fields of the links of games to their IDs on external services.
+	EXTERNAL_GAME_FIELDS = "game,external_game_source,uid,name,url"
+
+	// The most external IDs of a game fetched at once, the IGDB's maximum limit.
+	MAX_EXTERNAL_GAMES = 500
+)
+
+// externalGameSourceIDs maps the external services games are linked to by the external_games
+// endpoint to their id in the external_game_sources endpoint, which replaced the deprecated
+// category of external games with the same ids.
+var externalGameSourceIDs = map[string]int{
+	"steam":               1,
+	"gog":                 5,
+	"youtube":             10,
+	"microsoft":           11,
+	"apple":               13,
+	"twitch":              14,
+	"android":             15,
+	"amazon_asin":         20,
+	"amazon_luna":         22,
+	"amazon_adg":          23,
+	"epic_game_store":     26,
+	"oculus":              28,
+	"utomik":              29,
+	"itch_io":             30,
+	"xbox_marketplace":    31,
+	"kartridge":           32,
+	"playstation_store":   36,
+	"focus_entertainment": 37,
+	"xbox_game_pass":      54,
+	"gamejolt":            55,
+}
+
+// ExternalGame is a record of the IGDB external_games endpoint, linking a game to its ID on an
+// external service such as Steam or GOG.
+type ExternalGame struct {
+	ID     int64  `json:"id"`
+	Game   int64  `json:"game"`
+	Source int    `json:"external_game_source"`
+	UID    string `json:"uid"`
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+// externalGameSources returns the names of the external services games may be cross-referenced to.
+func externalGameSources() []string {
+	sources := []string{}
+	for source := range externalGameSourceIDs {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// validateCrossRefSource validates that the external service is known.
+func validateCrossRefSource(source string) error {
+	if _, ok := externalGameSourceIDs[source]; !ok {
+		return fmt.Errorf("unknown -cross-ref service %q, expected one of %s", source, strings.Join(externalGameSources(), ", "))
+	}
+	return nil
+}
+
+// QueryExternalGames queries the IDs of the game on the external service, e.g. its Steam app ID.
+func (d *DatabaseClient) QueryExternalGames(ctx context.Context, gameID int64, source string) ([]ExternalGame, error) {
+	sourceID, ok := externalGameSourceIDs[source]
+	if !ok {
+		return nil, validateCrossRefSource(source)
+	}
+
+	query := fmt.Sprintf("where game = %d & external_game_source = %d; limit %d;", gameID, sourceID, MAX_EXTERNAL_GAMES)
+	externalGames := []ExternalGame{}
+	err := d.queryInto(ctx, "external_games", query, EXTERNAL_GAME_FIELDS, &externalGames)
+	if err != nil {
+		return nil, err
+	}
+	return externalGames, nil
+}
+
+// runCrossRef prints the IDs on the external service of the game with the id given as the only
+// argument, one per line.
+func runCrossRef(opts *options, httpClient *http.Client) {
+	if len(opts.args) != 1 {
+		handleErr("failed to cross-reference the game", errors.New("-cross-ref requires the id of a game as the only argument"), BAD_USAGE_EXIT_CODE)
+	}
+	gameID, err := strconv.ParseInt(opts.args[0], 10, 64)
+	if err != nil || gameID <= 0 {
+		handleErr("failed to cross-reference the game", fmt.Errorf("invalid game id %q", opts.args[0]), BAD_USAGE_EXIT_CODE)
+	}
+
+	ctx, cancel := newRunContext(opts)
+	defer cancel()
+	databaseClient := newDatabaseClient(ctx, opts, httpClient)
+
+	externalGames, err := databaseClient.QueryExternalGames(ctx, gameID, opts.crossRef)
+	if err != nil {
+		explainRateLimit(opts, databaseClient)
+		handleQueryErr(opts, "failed to cross-reference the game", err)
+	}
+	if len(externalGames) == 0 {
+		handleErr("failed to cross-reference the game", fmt.Errorf("the game %d has no %s id", gameID, opts.crossRef), INTERNAL_ERROR_EXIT_CODE)
+	}
+
+	uids := make([]string, len(externalGames))
+	for i, externalGame := range externalGames {
+		uids[i] = externalGame.UID
+	}
+	printOutput(opts, strings.Join(uids, "\n"))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestQueryExternalGamesFiltersBySource(t *testing.T) {
+	var query string
+	databaseClient, _ := newTestClient(t, parseTestOptions(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query = string(body)
+		io.WriteString(w, `[{"id":9,"game":1942,"external_game_source":1,"uid":"292030"}]`)
+	}))
+
+	externalGames, err := databaseClient.QueryExternalGames(context.Background(), 1942, "steam")
+	if err != nil {
+		t.Fatalf("query failed: %s", err.Error())
+	}
+	if !strings.Contains(query, "external_game_source = 1") || strings.Contains(query, "category") {
+		t.Errorf("expected the query to filter by external_game_source, got %q", query)
+	}
+	if len(externalGames) != 1 || externalGames[0].Source != 1 || externalGames[0].UID != "292030" {
+		t.Errorf("expected the decoded Steam id, got %+v", externalGames)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// The layout and timezone the timestamps are rendered in by default.
+	DEFAULT_DATE_LAYOUT = time.RFC3339
+	DEFAULT_DATE_TZ     = "UTC"
+)
+
+// timestampFields are the fields of the IGDB holding unix timestamps, at any level of the records.
+var timestampFields = map[string]bool{
+	"change_date":        true,
+	"created_at":         true,
+	"date":               true,
+	"end_time":           true,
+	"first_release_date": true,
+	"published_at":       true,
+	"start_date":         true,
+	"start_time":         true,
+	"updated_at":         true,
+}
+
+// parseDateLocation validates the Go time layout and loads the timezone the timestamps are
+// rendered in, e.g. Europe/Berlin or Local.
+func parseDateLocation(layout string, tz string) (*time.Location, error) {
+	if layout == "" {
+		return nil, errors.New("-date-layout must not be empty")
+	}
+	// A layout without any elements of the reference time renders every timestamp identically, so
+	// renders a time differing in every element the same as the reference time.
+	reference := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+	other := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.FixedZone("", 3600))
+	if other.Format(layout) == reference.Format(layout) {
+		return nil, fmt.Errorf("-date-layout %q has no elements of the reference time Mon Jan 2 15:04:05 MST 2006", layout)
+	}
+	location, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown -date-tz %q, expected an IANA timezone like Europe/Berlin, UTC or Local", tz)
+	}
+	return location, nil
+}
+
+// humanizeDates replaces the unix timestamps of every object at every level with the time they
+// represent in the layout and location, leaving values that aren't integers as they are.
+func humanizeDates(value interface{}, layout string, location *time.Location) interface{} {
+	switch nested := value.(type) {
+	case []interface{}:
+		for _, element := range nested {
+			humanizeDates(element, layout, location)
+		}
+	case map[string]interface{}:
+		for field, nestedValue := range nested {
+			if number, ok := nestedValue.(json.Number); ok && timestampFields[field] {
+				if seconds, err := number.Int64(); err == nil {
+					nested[field] = time.Unix(seconds, 0).In(location).Format(layout)
+				}
+				continue
+			}
+			humanizeDates(nestedValue, layout, location)
+		}
+	}
+	return value
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestParseDateLocationLayouts(t *testing.T) {
+	cases := []struct {
+		name   string
+		layout string
+		valid  bool
+	}{
+		{"default", DEFAULT_DATE_LAYOUT, true},
+		{"date only", "2006-01-02", true},
+		{"dotted date", "02.01.2006", true},
+		{"named month", "Jan 2, 2006", true},
+		{"time only", "15:04", true},
+		{"weekday only", "Monday", true},
+		{"timezone only", "-0700", true},
+		{"literal only", "release date", false},
+		{"punctuation only", "--", false},
+		{"empty", "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := parseDateLocation(c.layout, DEFAULT_DATE_TZ)
+			if (err == nil) != c.valid {
+				t.Errorf("expected %q valid %t, got %v", c.layout, c.valid, err)
+			}
+		})
+	}
+}
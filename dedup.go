@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// dedupRecords removes the records sharing the value of the dotted field path with an earlier
+// record, keeping the first occurrence, e.g. duplicates at the boundaries of merged pages.
+// Records missing the field are always kept.
+func dedupRecords(result interface{}, path string) interface{} {
+	records, ok := result.([]interface{})
+	if !ok {
+		return result
+	}
+
+	seen := map[string]bool{}
+	deduped := []interface{}{}
+	for _, record := range records {
+		values := resolvePath(record, strings.Split(path, "."))
+		if len(values) == 0 {
+			deduped = append(deduped, record)
+			continue
+		}
+		key, err := json.Marshal(values)
+		if err != nil {
+			deduped = append(deduped, record)
+			continue
+		}
+		if seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+		deduped = append(deduped, record)
+	}
+	return deduped
+}
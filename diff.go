@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// recordDiff describes the differences between two sets of records keyed by their id.
+type recordDiff struct {
+	Added   []map[string]interface{} `json:"added"`
+	Removed []map[string]interface{} `json:"removed"`
+	Changed []recordChange           `json:"changed"`
+}
+
+// recordChange describes a record whose fields differ between two sets of records.
+type recordChange struct {
+	ID     interface{}            `json:"id"`
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+}
+
+// decodeRecords decodes a JSON array of records, preserving numbers exactly.
+func decodeRecords(data string) ([]map[string]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+
+	records := []map[string]interface{}{}
+	err := decoder.Decode(&records)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// indexRecords indexes the given records by their id.
+func indexRecords(records []map[string]interface{}) (map[string]map[string]interface{}, error) {
+	index := map[string]map[string]interface{}{}
+	for i, record := range records {
+		id, ok := record["id"]
+		if !ok {
+			return nil, fmt.Errorf("record at index %d has no id", i)
+		}
+		index[fmt.Sprint(id)] = record
+	}
+
+	return index, nil
+}
+
+// diffRecords computes the added, removed and changed records between two JSON arrays.
+func diffRecords(before string, after string) (*recordDiff, error) {
+	beforeRecords, err := decodeRecords(before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode previous records: %s", err.Error())
+	}
+	afterRecords, err := decodeRecords(after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode current records: %s", err.Error())
+	}
+
+	beforeIndex, err := indexRecords(beforeRecords)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index previous records: %s", err.Error())
+	}
+	afterIndex, err := indexRecords(afterRecords)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index current records: %s", err.Error())
+	}
+
+	// Walk the current records for additions and changes, and the previous for removals.
+	diff := &recordDiff{
+		Added:   []map[string]interface{}{},
+		Removed: []map[string]interface{}{},
+		Changed: []recordChange{},
+	}
+	for _, record := range afterRecords {
+		previous, ok := beforeIndex[fmt.Sprint(record["id"])]
+		if !ok {
+			diff.Added = append(diff.Added, record)
+		} else if !reflect.DeepEqual(previous, record) {
+			diff.Changed = append(diff.Changed, recordChange{ID: record["id"], Before: previous, After: record})
+		}
+	}
+	for _, record := range beforeRecords {
+		if _, ok := afterIndex[fmt.Sprint(record["id"])]; !ok {
+			diff.Removed = append(diff.Removed, record)
+		}
+	}
+
+	return diff, nil
+}
+
+// printDiff computes and prints the diff between two JSON arrays of records.
+func printDiff(opts *options, before string, after string) error {
+	diff, err := diffRecords(before, after)
+	if err != nil {
+		return err
+	}
+
+	diffBytes, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	writeStdout(fmt.Sprintf("%s \n%s", colorize(opts, ANSI_BOLD, "Diff result:"), terminateOutput(opts, string(diffBytes))))
+	return nil
+}
+
+// runLiveDiff diffs the live query result against a snapshot file.
+func runLiveDiff(opts *options, snapshotPath string, queryResult string) {
+	snapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		handleErr("failed to read the diff snapshot", err, BAD_USAGE_EXIT_CODE)
+	}
+
+	err = printDiff(opts, string(snapshot), queryResult)
+	if err != nil {
+		handleErr("failed to diff the query result", err, INTERNAL_ERROR_EXIT_CODE)
+	}
+}
+
+// runSnapshotDiff diffs two snapshot files against one another.
+func runSnapshotDiff(opts *options, beforePath string, afterPath string) {
+	before, err := os.ReadFile(beforePath)
+	if err != nil {
+		handleErr("failed to read the diff snapshot", err, BAD_USAGE_EXIT_CODE)
+	}
+	after, err := os.ReadFile(afterPath)
+	if err != nil {
+		handleErr("failed to read the diff snapshot", err, BAD_USAGE_EXIT_CODE)
+	}
+
+	err = printDiff(opts, string(before), string(after))
+	if err != nil {
+		handleErr("failed to diff the snapshots", err, INTERNAL_ERROR_EXIT_CODE)
+	}
+}
+
+// changedRecords returns the records of the current JSON array that are new or changed since the
+// previous one, in their current order. Records updated at the same time as before are unchanged,
+// while those without an updated_at are compared field by field.
+func changedRecords(before string, after string) ([]map[string]interface{}, error) {
+	beforeRecords, err := decodeRecords(before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode previous records: %s", err.Error())
+	}
+	afterRecords, err := decodeRecords(after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode current records: %s", err.Error())
+	}
+	beforeIndex, err := indexRecords(beforeRecords)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index previous records: %s", err.Error())
+	}
+
+	changed := []map[string]interface{}{}
+	for _, record := range afterRecords {
+		previous, ok := beforeIndex[fmt.Sprint(record["id"])]
+		if !ok {
+			changed = append(changed, record)
+			continue
+		}
+		previousUpdatedAt, hadUpdatedAt := previous["updated_at"]
+		updatedAt, hasUpdatedAt := record["updated_at"]
+		if hadUpdatedAt && hasUpdatedAt {
+			if fmt.Sprint(previousUpdatedAt) != fmt.Sprint(updatedAt) {
+				changed = append(changed, record)
+			}
+		} else if !reflect.DeepEqual(previous, record) {
+			changed = append(changed, record)
+		}
+	}
+	return changed, nil
+}
+
+// onlyChangedResult narrows the query result to the records that are new or changed since the
+// snapshot, which is then replaced by the full result when updating it. A missing snapshot is
+// treated as empty, so the first run of a series returns every record.
+func onlyChangedResult(opts *options, snapshotPath string, queryResult string) (string, error) {
+	snapshot, err := os.ReadFile(snapshotPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		snapshot = []byte("[]")
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read the snapshot: %s", err.Error())
+	}
+
+	changed, err := changedRecords(string(snapshot), queryResult)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.updateSnapshot {
+		err = os.WriteFile(snapshotPath, []byte(strings.TrimRight(queryResult, "\n")+"\n"), 0644)
+		if err != nil {
+			return "", fmt.Errorf("failed to update the snapshot: %s", err.Error())
+		}
+	}
+	return encodeJSON(changed)
+}
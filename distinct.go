@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Constants for printing the histogram of -count-distinct.
+	HISTOGRAM_BAR_WIDTH = 40
+	MISSING_VALUE_LABEL = "<missing>"
+)
+
+// distinctCount is the number of records holding a distinct value of the counted field.
+type distinctCount struct {
+	value string
+	count int
+}
+
+// countDistinct tallies the distinct values of the dotted field path across the records of the
+// result, where each element of an array along the path is counted separately.
+func countDistinct(result interface{}, path string) ([]distinctCount, error) {
+	records, ok := result.([]interface{})
+	if !ok {
+		records = []interface{}{result}
+	}
+
+	tally := map[string]int{}
+	for _, record := range records {
+		values := resolvePath(record, strings.Split(path, "."))
+		if len(values) == 0 {
+			tally[MISSING_VALUE_LABEL]++
+		}
+		for _, value := range values {
+			cell, err := formatCell(value)
+			if err != nil {
+				return nil, err
+			}
+			tally[cell]++
+		}
+	}
+
+	return sortCounts(tally), nil
+}
+
+// sortCounts orders the tally of values from the most to the least common, breaking ties by value.
+func sortCounts(tally map[string]int) []distinctCount {
+	counts := []distinctCount{}
+	for value, count := range tally {
+		counts = append(counts, distinctCount{value: value, count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].count != counts[j].count {
+			return counts[i].count > counts[j].count
+		}
+		return counts[i].value < counts[j].value
+	})
+	return counts
+}
+
+// resolvePath returns the values found at the path within the value, fanning out over arrays.
+func resolvePath(value interface{}, path []string) []interface{} {
+	if array, ok := value.([]interface{}); ok {
+		values := []interface{}{}
+		for _, element := range array {
+			values = append(values, resolvePath(element, path)...)
+		}
+		return values
+	}
+	if len(path) == 0 {
+		if value == nil {
+			return nil
+		}
+		return []interface{}{value}
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	nested, ok := object[path[0]]
+	if !ok {
+		return nil
+	}
+	return resolvePath(nested, path[1:])
+}
+
+// formatHistogram formats the counts as a histogram, one distinct value per line beneath the
+// number of them.
+func formatHistogram(path string, counts []distinctCount) string {
+	header := fmt.Sprintf("%d distinct values of %s", len(counts), path)
+	if len(counts) == 0 {
+		return header
+	}
+	return header + "\n" + formatHistogramLines(counts)
+}
+
+// formatHistogramLines formats the lines of the histogram of the counts, without a header.
+func formatHistogramLines(counts []distinctCount) string {
+	if len(counts) == 0 {
+		return ""
+	}
+	max := counts[0].count
+	countWidth := len(fmt.Sprint(max))
+	valueWidth := 0
+	for _, count := range counts {
+		if len(count.value) > valueWidth {
+			valueWidth = len(count.value)
+		}
+	}
+
+	lines := []string{}
+	for _, count := range counts {
+		bar := strings.Repeat("#", (count.count*HISTOGRAM_BAR_WIDTH+max-1)/max)
+		lines = append(lines, fmt.Sprintf("%*d  %-*s  %s", countWidth, count.count, valueWidth, count.value, bar))
+	}
+	return strings.Join(lines, "\n")
+}
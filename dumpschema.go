@@ -0,0 +1,45 @@
+package main
+
+import "sort"
+
+// schemaDump is the static knowledge of the IGDB built into the program, printed by -dump-schema.
+type schemaDump struct {
+	Endpoints       map[string]*endpointSchema `json:"endpoints"`
+	Aliases         map[string]string          `json:"aliases"`
+	TimestampFields []string                   `json:"timestamp_fields"`
+}
+
+// endpointSchema is the static knowledge of an endpoint, omitting what isn't known about it.
+type endpointSchema struct {
+	Fields           []string                     `json:"fields,omitempty"`
+	DefaultFields    string                       `json:"default_fields,omitempty"`
+	Enums            map[string]map[string]string `json:"enums,omitempty"`
+	DeprecatedFields map[string]string            `json:"deprecated_fields,omitempty"`
+}
+
+// dumpSchema collects the known endpoints along with their fields, default fields, enum labels
+// and deprecated fields, the built-in endpoint aliases and the fields holding timestamps.
+func dumpSchema() *schemaDump {
+	dump := &schemaDump{
+		Endpoints:       map[string]*endpointSchema{},
+		Aliases:         defaultEndpointAliases,
+		TimestampFields: []string{},
+	}
+	for _, endpoint := range knownEndpoints {
+		schema := &endpointSchema{
+			DefaultFields:    defaultFieldPresets[endpoint],
+			Enums:            enumLabels[endpoint],
+			DeprecatedFields: deprecatedFields[endpoint],
+		}
+		// Every endpoint has an id, which the static schema leaves out.
+		if fields, ok := endpointFields[endpoint]; ok {
+			schema.Fields = append([]string{"id"}, fields...)
+		}
+		dump.Endpoints[endpoint] = schema
+	}
+	for field := range timestampFields {
+		dump.TimestampFields = append(dump.TimestampFields, field)
+	}
+	sort.Strings(dump.TimestampFields)
+	return dump
+}
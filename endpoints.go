@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Suffix of the endpoints counting the matching records, e.g. games/count.
+	COUNT_ENDPOINT_SUFFIX = "/count"
+)
+
+// knownEndpoints are the endpoints of the IGDB recognized by the program.
+var knownEndpoints = []string{
+	"age_rating_content_descriptions",
+	"age_ratings",
+	"alternative_names",
+	"artworks",
+	"character_mug_shots",
+	"characters",
+	"collections",
+	"companies",
+	"company_logos",
+	"company_websites",
+	"covers",
+	"events",
+	"external_games",
+	"franchises",
+	"game_engines",
+	"game_localizations",
+	"game_modes",
+	"game_versions",
+	"game_videos",
+	"games",
+	"genres",
+	"involved_companies",
+	"keywords",
+	"languages",
+	MULTIQUERY_ENDPOINT,
+	"multiplayer_modes",
+	"platform_families",
+	"platform_logos",
+	"platform_versions",
+	"platform_websites",
+	"platforms",
+	"player_perspectives",
+	"regions",
+	"release_dates",
+	"screenshots",
+	SEARCH_ENDPOINT,
+	"themes",
+	"websites",
+}
+
+const (
+	// Separates an endpoint from a field of -fields selected only for it, e.g. games:rating.
+	ENDPOINT_FIELD_SEPARATOR = ":"
+)
+
+// defaultFieldPresets are the fields selected for endpoints by default when the query selects
+// none, since the IGDB would otherwise return only the ids.
+var defaultFieldPresets = map[string]string{
+	"artworks":           "game,image_id,width,height",
+	"external_games":     EXTERNAL_GAME_FIELDS,
+	"game_localizations": "name,game,region,cover",
+	"languages":          "name,native_name,locale",
+	"regions":            "name,identifier,category",
+	"screenshots":        "game,image_id,width,height",
+}
+
+// defaultEndpointAliases are the built-in shorthands for common endpoints, which may be overridden
+// or extended by the aliases in the config file.
+var defaultEndpointAliases = map[string]string{
+	"c":   "companies",
+	"cv":  "covers",
+	"g":   "games",
+	"gen": "genres",
+	"ic":  "involved_companies",
+	"k":   "keywords",
+	"mq":  MULTIQUERY_ENDPOINT,
+	"p":   "platforms",
+	"rd":  "release_dates",
+}
+
+// resolveEndpoint resolves an endpoint alias, including those of count endpoints, e.g. g/count.
+func resolveEndpoint(endpoint string, aliases map[string]string) string {
+	count := strings.HasSuffix(endpoint, COUNT_ENDPOINT_SUFFIX)
+	resolved, ok := aliases[strings.TrimSuffix(endpoint, COUNT_ENDPOINT_SUFFIX)]
+	if !ok {
+		return endpoint
+	}
+	if count {
+		return resolved + COUNT_ENDPOINT_SUFFIX
+	}
+	return resolved
+}
+
+// isKnownEndpoint reports whether the endpoint, or the endpoint it counts, is recognized.
+func isKnownEndpoint(endpoint string) bool {
+	endpoint = strings.TrimSuffix(endpoint, COUNT_ENDPOINT_SUFFIX)
+	for _, known := range knownEndpoints {
+		if endpoint == known {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultFields returns the fields selected for the endpoint when the query selects none: its
+// preset merged with the -fields for every endpoint and those prefixed with its name, e.g.
+// games:rating, falling back to every field with -all-fields. Empty means the IGDB's default.
+func defaultFields(opts *options, endpoint string) string {
+	fields := []string{}
+	seen := map[string]bool{}
+	add := func(field string) {
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+
+	if preset, ok := defaultFieldPresets[endpoint]; ok {
+		for _, field := range splitFields(preset) {
+			add(field)
+		}
+	}
+	for _, field := range splitFields(opts.fields) {
+		fieldEndpoint, endpointField, ok := strings.Cut(field, ENDPOINT_FIELD_SEPARATOR)
+		if !ok {
+			add(field)
+		} else if fieldEndpoint == endpoint {
+			add(endpointField)
+		}
+	}
+
+	if len(fields) == 0 && opts.allFields {
+		return "*"
+	}
+	return strings.Join(fields, ",")
+}
+
+// applyDefaultFields selects the endpoint's default fields when the query selects none, or every
+// field with -fields-exclude.
+func applyDefaultFields(opts *options, endpoint string, q *apicalypseQuery) {
+	if _, ok := q.get("fields"); ok {
+		return
+	}
+	// Every field is fetched, with the excluded ones stripped from the results.
+	if opts.fieldsExclude != "" {
+		q.set("fields", "*")
+		return
+	}
+	if fields := defaultFields(opts, endpoint); fields != "" {
+		q.set("fields", fields)
+	}
+}
+
+// listEndpoints formats the known endpoints one per line, along with their preset fields.
+func listEndpoints() string {
+	lines := []string{}
+	for _, endpoint := range knownEndpoints {
+		if preset, ok := defaultFieldPresets[endpoint]; ok {
+			lines = append(lines, fmt.Sprintf("%s\t(default fields: %s)", endpoint, preset))
+			continue
+		}
+		lines = append(lines, endpoint)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validateEndpoint validates that the endpoint is recognized.
+func validateEndpoint(endpoint string) error {
+	if !isKnownEndpoint(endpoint) {
+		return fmt.Errorf("unknown endpoint %q", endpoint)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocalizationEndpoints(t *testing.T) {
+	listed := map[string]string{}
+	for _, line := range strings.Split(listEndpoints(), "\n") {
+		listed[strings.SplitN(line, "\t", 2)[0]] = line
+	}
+
+	for _, endpoint := range []string{"game_localizations", "regions", "languages"} {
+		t.Run(endpoint, func(t *testing.T) {
+			err := validateEndpoint(endpoint)
+			if err != nil {
+				t.Errorf("expected the endpoint to be valid, got %s", err.Error())
+			}
+			preset, ok := defaultFieldPresets[endpoint]
+			if !ok || preset == "" {
+				t.Fatal("expected the endpoint to have default fields")
+			}
+			line, ok := listed[endpoint]
+			if !ok {
+				t.Fatal("expected the endpoint to be listed by -list-endpoints")
+			}
+			if !strings.Contains(line, preset) {
+				t.Errorf("expected -list-endpoints to list the default fields %s, got %q", preset, line)
+			}
+		})
+	}
+}
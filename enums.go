@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	// Suffix of the keys holding the labels of decoded enums, unless decoded in place.
+	ENUM_LABEL_SUFFIX = "_label"
+)
+
+// enumLabels maps the endpoints to their integer enum fields, and each value to its label.
+var enumLabels = map[string]map[string]map[string]string{
+	"age_ratings": {
+		"category": {"1": "ESRB", "2": "PEGI", "3": "CERO", "4": "USK", "5": "GRAC", "6": "CLASS_IND", "7": "ACB"},
+	},
+	"external_games": {
+		"category": {
+			"1": "steam", "5": "gog", "10": "youtube", "11": "microsoft", "13": "apple", "14": "twitch",
+			"15": "android", "20": "amazon_asin", "22": "amazon_luna", "23": "amazon_adg", "26": "epic_game_store",
+			"28": "oculus", "29": "utomik", "30": "itch_io", "31": "xbox_marketplace", "32": "kartridge",
+			"36": "playstation_store", "37": "focus_entertainment", "54": "xbox_game_pass", "55": "gamejolt",
+		},
+	},
+	"games": {
+		"category": {
+			"0": "main_game", "1": "dlc_addon", "2": "expansion", "3": "bundle", "4": "standalone_expansion",
+			"5": "mod", "6": "episode", "7": "season", "8": "remake", "9": "remaster", "10": "expanded_game",
+			"11": "port", "12": "fork", "13": "pack", "14": "update",
+		},
+		"status": {
+			"0": "released", "2": "alpha", "3": "beta", "4": "early_access", "5": "offline", "6": "cancelled",
+			"7": "rumored", "8": "delisted",
+		},
+	},
+	"platforms": {
+		"category": {"1": "console", "2": "arcade", "3": "platform", "4": "operating_system", "5": "portable_console", "6": "computer"},
+	},
+	"release_dates": {
+		"category": {
+			"0": "YYYYMMMMDD", "1": "YYYYMMMM", "2": "YYYY", "3": "YYYYQ1", "4": "YYYYQ2", "5": "YYYYQ3",
+			"6": "YYYYQ4", "7": "TBD",
+		},
+		"region": {
+			"1": "europe", "2": "north_america", "3": "australia", "4": "new_zealand", "5": "japan", "6": "china",
+			"7": "asia", "8": "worldwide", "9": "korea", "10": "brazil",
+		},
+	},
+	"websites": {
+		"category": {
+			"1": "official", "2": "wikia", "3": "wikipedia", "4": "facebook", "5": "twitter", "6": "twitch",
+			"8": "instagram", "9": "youtube", "10": "iphone", "11": "ipad", "12": "android", "13": "steam",
+			"14": "reddit", "15": "itch", "16": "epicgames", "17": "gog", "18": "discord",
+		},
+	},
+}
+
+// decodeEnums labels the known enum fields of the endpoint's records, either replacing the integers
+// in place or adding the labels alongside them. Unknown values are left as they are.
+func decodeEnums(endpoint string, result interface{}, inPlace bool) interface{} {
+	fields, ok := enumLabels[strings.TrimSuffix(endpoint, COUNT_ENDPOINT_SUFFIX)]
+	if !ok {
+		return result
+	}
+
+	switch value := result.(type) {
+	case []interface{}:
+		for _, record := range value {
+			decodeRecordEnums(fields, record, inPlace)
+		}
+	case map[string]interface{}:
+		decodeRecordEnums(fields, value, inPlace)
+	}
+	return result
+}
+
+// decodeRecordEnums labels the enum fields of a single record.
+func decodeRecordEnums(fields map[string]map[string]string, record interface{}, inPlace bool) {
+	object, ok := record.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for field, labels := range fields {
+		number, ok := object[field].(json.Number)
+		if !ok {
+			continue
+		}
+		label, ok := labels[number.String()]
+		if !ok {
+			continue
+		}
+		if inPlace {
+			object[field] = label
+		} else {
+			object[field+ENUM_LABEL_SUFFIX] = label
+		}
+	}
+}
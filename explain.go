@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// The number of throttled responses after which the rate limit is explained unprompted.
+	REPEATED_THROTTLING_THRESHOLD = 2
+)
+
+// explainRateLimit prints guidance on avoiding throttling to stderr once responses were throttled
+// repeatedly, or at all with -explain-rate-limit, reporting whether it did.
+func explainRateLimit(opts *options, databaseClient *DatabaseClient) bool {
+	throttled := databaseClient.ThrottledCount()
+	if throttled == 0 || (!opts.explainRateLimit && throttled < REPEATED_THROTTLING_THRESHOLD) {
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "%s\n", rateLimitExplanation(opts, databaseClient))
+	return true
+}
+
+// rateLimitExplanation explains the throttled responses of the client, along with suggestions for
+// avoiding them that the options haven't already taken.
+func rateLimitExplanation(opts *options, databaseClient *DatabaseClient) string {
+	throttled := databaseClient.ThrottledCount()
+	credentials := len(databaseClient.credentials)
+	lines := []string{fmt.Sprintf("The IGDB throttled %d responses with 429 Too Many Requests.", throttled)}
+	if opts.rateLimit > 0 {
+		lines = append(lines, fmt.Sprintf("Requests are limited to %g per second for each of %d credentials, %g per second in total.", opts.rateLimit, credentials, opts.rateLimit*float64(credentials)))
+	} else {
+		lines = append(lines, "Requests aren't rate limited, as -rate-limit is 0.")
+	}
+	if status := databaseClient.LastRateLimitStatus(); status != nil {
+		lines = append(lines, fmt.Sprintf("The latest response reported the rate limit as: %s.", status.String()))
+	}
+
+	lines = append(lines, "To avoid throttling:")
+	if opts.rateLimit <= 0 || opts.rateLimit > DEFAULT_IGDB_RATE_LIMIT {
+		lines = append(lines, fmt.Sprintf("  - limit requests to the IGDB's %d per second with -rate-limit %d", DEFAULT_IGDB_RATE_LIMIT, DEFAULT_IGDB_RATE_LIMIT))
+	} else {
+		lines = append(lines, "  - lower -rate-limit, since other clients may share the credentials")
+	}
+	if opts.concurrency > 1 {
+		lines = append(lines, "  - lower -concurrency")
+	}
+	if opts.parallelPages {
+		lines = append(lines, "  - drop -parallel-pages to fetch pages in turn")
+	}
+	if opts.cache <= 0 {
+		lines = append(lines, "  - serve repeated queries from the response cache with -cache <ttl>, e.g. -cache 1h")
+	}
+
+	return strings.Join(lines, "\n")
+}
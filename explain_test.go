@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRateLimitExplanationSuggestions(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		suggests []string
+		omits    []string
+	}{
+		{"defaults", nil, []string{"-cache <ttl>"}, []string{"-multi-credentials"}},
+		{"unlimited", []string{"-rate-limit", "0"}, []string{"-rate-limit 4", "-cache <ttl>"}, nil},
+		{"cached", []string{"-cache", "1h"}, nil, []string{"-cache <ttl>"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := parseTestOptions(t, c.args...)
+			databaseClient := NewDatabaseClient("client-id", "auth-token")
+			databaseClient.throttled = 3
+
+			explanation := rateLimitExplanation(opts, databaseClient)
+			for _, suggestion := range c.suggests {
+				if !strings.Contains(explanation, suggestion) {
+					t.Errorf("expected the explanation to suggest %q:\n%s", suggestion, explanation)
+				}
+			}
+			for _, suggestion := range c.omits {
+				if strings.Contains(explanation, suggestion) {
+					t.Errorf("expected the explanation not to suggest %q:\n%s", suggestion, explanation)
+				}
+			}
+		})
+	}
+}
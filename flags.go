@@ -0,0 +1,686 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// The label printed before the results by default.
+	DEFAULT_BANNER = "Query result:"
+
+	// Environment variable containing default flags, overridden by those on the command line.
+	DEFAULT_OPTIONS_ENV_VAR = "GAMERS_CONSOLE_OPTS"
+)
+
+// flags is the set of command line flags supported by the program.
+var flags = newFlagSet()
+
+// options holds the values of the parsed command line flags and arguments.
+type options struct {
+	// Request and logging behaviour.
+	accept          string
+	verbose         bool
+	trace           bool
+	record          string
+	recordSecrets   bool
+	maxResponseSize int64
+	rateLimit       float64
+	retries         int
+	retryDelay      time.Duration
+	retryBudget     int
+	metricsOut      string
+	prettyErrors    bool
+
+	// Re-running queries returning no records, in case just published records appear.
+	retryOnEmpty      int
+	retryOnEmptyDelay time.Duration
+
+	// Whether to explain the rate limit after any throttled response, not only repeated ones.
+	explainRateLimit bool
+
+	// Transport settings shared by every request.
+	proxy              string
+	caCert             string
+	insecureSkipVerify bool
+
+	// Tuning of the connections reused by the transport.
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	http2               bool
+
+	// Timeouts of connecting, the TLS handshake, awaiting response headers and each request.
+	dialTimeout           time.Duration
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration
+	requestTimeout        time.Duration
+
+	// A pre-fetched access token used in place of authenticating.
+	accessToken string
+
+	// Whether to authorize in the browser rather than with the client secret.
+	interactiveAuth bool
+
+	// Whether to print the auth token and exit.
+	printToken bool
+
+	// Whether to spread requests across several client IDs.
+	multiCredentials bool
+
+	// Paging through every matching record.
+	all           bool
+	since         string
+	parallelPages bool
+	timeout       time.Duration
+
+	// Whether to skip confirming large pulls.
+	yes bool
+
+	// Whether to cap limits and disable paging, protecting the quota of shared credentials.
+	safeMode bool
+
+	// An external command to pipe the output through.
+	pipe string
+
+	// An external command formatting the JSON output in place of the built-in formats.
+	formatter string
+
+	// An expression the count of matching records must satisfy, e.g. >100.
+	assertCount string
+
+	// The most time allowed since the newest record was updated.
+	maxAge time.Duration
+
+	// How long cached responses are served for, or zero to disable the cache, and whether to serve
+	// expired responses while refreshing them.
+	cache time.Duration
+	swr   bool
+
+	// Whether to clear every cached response or those of an endpoint, or to report on the cache.
+	cacheClear         bool
+	cacheClearEndpoint string
+	cacheStats         bool
+
+	// Whether to stream the records as NDJSON.
+	stream bool
+
+	// Whether to omit the newline ending the output.
+	noTrailingNewline bool
+
+	// The label printed before the results.
+	banner string
+
+	// Path of the config file.
+	configPath string
+
+	// Endpoint aliases, from the defaults and the config file.
+	aliases map[string]string
+
+	// Query macros by name, from the config file.
+	macros map[string]string
+
+	// Running a batch of queries read from a file.
+	batch       string
+	concurrency int
+	noDedup     bool
+	outputDir   string
+	keepGoing   bool
+	errorLog    string
+
+	// Splitting the results into parts of -output-dir by their records or bytes.
+	splitSize  int
+	splitBytes string
+
+	// Re-running the query on an interval.
+	watch time.Duration
+	clear bool
+
+	// Alternative sources of the query.
+	queryFile      string
+	queryClipboard bool
+	queryStdinJSON bool
+	globalSearch   string
+
+	// Path of a file of IDs to fetch the records of, and how to order them.
+	idsFile              string
+	orderByInput         bool
+	missingIDPlaceholder string
+
+	// The limit applied to queries without one, or zero for the IGDB's default.
+	defaultLimit int
+
+	// The fields selected when the query selects none, optionally per endpoint, and whether to
+	// select every field otherwise.
+	fields    string
+	allFields bool
+
+	// Comma separated fields stripped from the results of selecting every field.
+	fieldsExclude string
+
+	// Whether to render the query as a template.
+	template bool
+
+	// Clauses shared by every query, overridden by the query's own.
+	baseQuery string
+
+	// Whether to rewrite deprecated fields to their current names.
+	migrateFields bool
+
+	// Whether to lint the query before it's sent, and whether warnings are fatal.
+	lint   bool
+	strict bool
+
+	// Whether to check for a newer release of the program.
+	checkUpdate bool
+
+	// Whether to run the smoke test against several endpoints.
+	smoke bool
+
+	// The id of a game to print the developers and publishers of.
+	gameCompanies string
+
+	// The external service to print the IDs of the game given as the argument on, e.g. steam.
+	crossRef string
+
+	// Whether to print the known endpoints.
+	listEndpoints bool
+
+	// Whether to print the static schema of the endpoints as JSON.
+	dumpSchema bool
+
+	// The endpoint to print the observed fields of.
+	suggestFields string
+
+	// The shell to print a completion script for.
+	completion string
+
+	// Whether to color the output: auto, always or never.
+	color string
+
+	// Whether to run with the defaults for CI, and the format of errors: text or json.
+	ci          bool
+	errorFormat string
+
+	// Whether to always or never page the output, rather than only on a terminal.
+	pager   bool
+	noPager bool
+
+	// Post-processing and formatting of the results.
+	flatten  bool
+	format   string
+	withMeta bool
+	single   bool
+	head     int
+	tail     int
+	sortKeys bool
+	pretty   bool
+	compact  bool
+
+	// Whether to fail unless the response is valid JSON.
+	validateJSON bool
+
+	// Path of a JSON Schema every record must be valid against.
+	schema string
+
+	// The dotted path of a field identifying duplicate records to remove.
+	dedupBy string
+
+	// Comma separated old:new pairs of fields to rename in the results.
+	rename string
+
+	// A JSON array of local records to join the results with, on which field and whether the local
+	// values override those of the results.
+	mergeWithFile string
+	mergeKey      string
+	mergeOverride bool
+
+	// Whether to add normalized variants of the fields, and which.
+	normalizeStrings bool
+	normalizeFields  string
+
+	// Whether to render unix timestamps as dates, in which layout and timezone.
+	humanizeDates bool
+	dateLayout    string
+	dateTZ        string
+
+	// Whether to add the URLs of images alongside their image IDs, and in which size.
+	withImageURLs bool
+	imageSize     string
+
+	// Whether to key the results of multiqueries by the names of their sub-queries.
+	multiqueryByName bool
+
+	// An RFC 6901 JSON Pointer to the part of the results to print.
+	pointer string
+
+	// Whether to remove null, and optionally empty, values from the results.
+	stripNulls bool
+	stripEmpty bool
+
+	// Whether to label known enum values, alongside or in place of the integers.
+	decodeEnums        bool
+	decodeEnumsInPlace bool
+
+	// The dotted path of a field to tally the distinct values of.
+	countDistinct string
+
+	// The dotted path of a field to summarize the values of.
+	summarize string
+
+	// Path of a SQLite database to write the results to.
+	sqlite string
+
+	// Snapshot files used to compute a diff of the results.
+	diff     string
+	diffWith string
+
+	// A snapshot to print only the records changed since, and whether to update it.
+	onlyChanged    string
+	updateSnapshot bool
+
+	// The remaining positional arguments, i.e. the endpoint and query.
+	args []string
+}
+
+// newFlagSet instantiates the flag set used to parse the command line.
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("gamers-console", flag.ContinueOnError)
+	fs.SetOutput(stdoutWriter{})
+	fs.Usage = func() {}
+	return fs
+}
+
+// parseOptions parses the given command line arguments into the program options.
+func parseOptions(args []string) (*options, error) {
+	opts := &options{}
+	flags.StringVar(&opts.accept, "accept", DEFAULT_IGDB_ACCEPT, "value of the Accept header sent with each query")
+	flags.BoolVar(&opts.verbose, "v", false, "log the details of each request to stderr")
+	flags.BoolVar(&opts.trace, "trace", false, "report the DNS, connect, TLS and first byte timings of each request to stderr")
+	flags.StringVar(&opts.record, "record", "", "path of a file to record every request and response to, with secrets redacted")
+	flags.BoolVar(&opts.recordSecrets, "record-secrets", false, "include auth tokens and client secrets in the -record file")
+	flags.Int64Var(&opts.maxResponseSize, "max-response-size", 0, "maximum size of a response body in bytes, or 0 for unlimited")
+	flags.StringVar(&opts.queryFile, "query-file", "", "path to a file containing the query, used in place of the positional query")
+	flags.StringVar(&opts.globalSearch, "global-search", "", "search for the term across games, companies, characters and more via the search endpoint")
+	flags.BoolVar(&opts.lint, "lint", false, "check the query for common mistakes before it's sent, printing warnings to stderr")
+	flags.BoolVar(&opts.strict, "strict", false, "exit without sending the query when -lint finds any warnings")
+	flags.StringVar(&opts.idsFile, "ids-file", "", "path to a file of newline separated IDs to fetch the records of, where the query is optional")
+	flags.BoolVar(&opts.orderByInput, "order-output-by-input", false, "order the records of -ids-file to match the order of the IDs")
+	flags.StringVar(&opts.missingIDPlaceholder, "missing-id-placeholder", "", "JSON value standing in for missing IDs under -order-output-by-input, e.g. null, rather than skipping them")
+	flags.IntVar(&opts.defaultLimit, "default-limit", 0, "limit applied to queries without one, rather than the IGDB's default of 10")
+	flags.StringVar(&opts.fields, "fields", "", "comma separated fields selected when the query selects none, merged with the endpoint's defaults, where endpoint:field selects the field only for that endpoint, e.g. name,games:rating")
+	flags.StringVar(&opts.fieldsExclude, "fields-exclude", "", "comma separated fields stripped from each record, selecting every field when the query selects none, e.g. summary,storyline, though they're still transferred")
+	flags.BoolVar(&opts.allFields, "all-fields", false, "select every field when the query selects none and neither -fields nor the endpoint's defaults apply")
+	flags.BoolVar(&opts.template, "template", false, "render the query as a Go template, where {{env \"VAR\"}} substitutes an environment variable and {{env \"VAR\" \"default\"}} a default when unset")
+	flags.StringVar(&opts.baseQuery, "base-query", "", "clauses to prepend to the query, e.g. \"fields name,rating;\", which the query's own clauses override")
+	flags.BoolVar(&opts.migrateFields, "migrate-fields", false, "rewrite known deprecated fields in the query to their current names, with a warning")
+	flags.BoolVar(&opts.queryStdinJSON, "query-stdin-json", false, "read the endpoint and query from a JSON spec on stdin, e.g. {\"endpoint\":\"games\",\"fields\":[\"name\"],\"limit\":10}")
+	flags.BoolVar(&opts.queryClipboard, "query-clipboard", false, "read the query from the system clipboard, used in place of the positional query")
+	flags.BoolVar(&opts.prettyErrors, "pretty-errors", true, "summarize structured error bodies returned by the IGDB, or print them raw when false")
+	flags.StringVar(&opts.proxy, "proxy", "", "URL of the proxy for every request, overriding HTTP_PROXY and HTTPS_PROXY")
+	flags.StringVar(&opts.caCert, "ca-cert", "", "path to a PEM encoded CA certificate to trust in addition to the system's")
+	flags.IntVar(&opts.maxIdleConns, "max-idle-conns", DEFAULT_MAX_IDLE_CONNS, "maximum number of idle connections kept open for reuse, where zero is unlimited")
+	flags.IntVar(&opts.maxIdleConnsPerHost, "max-idle-conns-per-host", DEFAULT_MAX_IDLE_CONNS_PER_HOST, "maximum number of idle connections kept open for reuse per host")
+	flags.DurationVar(&opts.idleConnTimeout, "idle-conn-timeout", DEFAULT_IDLE_CONN_TIMEOUT, "how long idle connections are kept open for reuse, where zero is forever")
+	flags.DurationVar(&opts.dialTimeout, "dial-timeout", DEFAULT_DIAL_TIMEOUT, "time allowed for connecting, where zero is no limit")
+	flags.DurationVar(&opts.tlsHandshakeTimeout, "tls-handshake-timeout", DEFAULT_TLS_HANDSHAKE_TIMEOUT, "time allowed for the TLS handshake, where zero is no limit")
+	flags.DurationVar(&opts.responseHeaderTimeout, "response-header-timeout", 0, "time allowed for the response headers once the request is sent, where zero is no limit")
+	flags.DurationVar(&opts.requestTimeout, "request-timeout", 0, "time allowed for each request including reading its response, where zero is no limit, unlike -timeout for the whole query")
+	flags.BoolVar(&opts.http2, "http2", true, "attempt HTTP/2, use -http2=false to force HTTP/1.1")
+	flags.BoolVar(&opts.explainRateLimit, "explain-rate-limit", false, "print guidance on avoiding throttling after any throttled response, rather than only after repeated ones")
+	flags.BoolVar(&opts.insecureSkipVerify, "insecure-skip-verify", false, "disable TLS certificate verification, only for testing against trusted sandboxes")
+	flags.StringVar(&opts.accessToken, "access-token", "", fmt.Sprintf("pre-fetched access token used in place of authenticating with the client secret, also read from %s", ACCESS_TOKEN_ENV_VAR))
+	flags.BoolVar(&opts.interactiveAuth, "interactive-auth", false, "authorize in the browser with the Twitch device code flow, needing only the client ID, caching the token until it expires")
+	flags.BoolVar(&opts.printToken, "print-token", false, "authenticate with the client secret and print the auth token and its expiry, then exit")
+	flags.BoolVar(&opts.multiCredentials, "multi-credentials", false, fmt.Sprintf("spread requests across the additional client credentials in %s, see below", TWITCH_CREDENTIALS_ENV_VAR))
+	flags.Float64Var(&opts.rateLimit, "rate-limit", DEFAULT_IGDB_RATE_LIMIT, "maximum requests per second, or 0 for unlimited")
+	flags.IntVar(&opts.retries, "retries", DEFAULT_RETRIES, "number of times to retry throttled and server error responses")
+	flags.IntVar(&opts.retryOnEmpty, "retry-on-empty", 0, "number of times to re-run a query returning no records, in case just published records appear, see below")
+	flags.DurationVar(&opts.retryOnEmptyDelay, "retry-on-empty-delay", DEFAULT_RETRY_ON_EMPTY_DELAY, "delay before re-running a query returning no records with -retry-on-empty")
+	flags.DurationVar(&opts.retryDelay, "retry-delay", DEFAULT_RETRY_DELAY, "base delay before retrying, doubled each retry with random jitter, unless the response gives a Retry-After")
+	flags.IntVar(&opts.retryBudget, "retry-budget", 0, "most retries across every request of the run, e.g. with -all or -batch, after which failed responses aren't retried, or 0 for unlimited")
+	flags.StringVar(&opts.metricsOut, "metrics-out", "", "path to write the counts and latencies of the requests to in the Prometheus text format after the run, e.g. for the node_exporter textfile collector")
+	flags.BoolVar(&opts.all, "all", false, "page through every record matching the query, merging the pages")
+	flags.BoolVar(&opts.parallelPages, "parallel-pages", false, "count the records first so that -all and -since may fetch pages concurrently, up to the rate limit")
+	flags.StringVar(&opts.since, "since", "", "page through the records updated since the given date, timestamp or epoch, printing the new high-water mark to stderr")
+	flags.StringVar(&opts.banner, "banner", DEFAULT_BANNER, "label printed before the results, or empty for none")
+	flags.StringVar(&opts.configPath, "config", "", "path to the config file (default is gamers-console/config.json in the user config directory)")
+	flags.DurationVar(&opts.timeout, "timeout", 0, "overall time allowed for the query, e.g. 2m, or 0 for no limit")
+	flags.BoolVar(&opts.safeMode, "safe-mode", false, fmt.Sprintf("cap limits to %d, require -yes for limits over %d and disable paging, also enabled by %s", SAFE_MODE_MAX_LIMIT, SAFE_MODE_CONFIRM_LIMIT, SAFE_MODE_ENV_VAR))
+	flags.BoolVar(&opts.yes, "yes", false, fmt.Sprintf("skip confirming paging through more than %d requests of records, which is only asked on a terminal, and allow limits over %d in safe mode", CONFIRM_REQUEST_THRESHOLD, SAFE_MODE_CONFIRM_LIMIT))
+	flags.StringVar(&opts.batch, "batch", "", "path to a file of queries to run, one endpoint and query per line separated by whitespace")
+	flags.IntVar(&opts.concurrency, "concurrency", 1, "number of batch queries to run at once, limited to what -rate-limit can sustain")
+	flags.StringVar(&opts.outputDir, "output-dir", "", "directory to write each batch result, or each part of -split-size and -split-bytes, to its own file, along with a manifest")
+	flags.IntVar(&opts.splitSize, "split-size", 0, "write the results to -output-dir as NDJSON parts of at most this many records")
+	flags.StringVar(&opts.splitBytes, "split-bytes", "", "write the results to -output-dir as NDJSON parts of at most this size, e.g. 50MB")
+	flags.BoolVar(&opts.keepGoing, "keep-going", false, "keep running the batch after an entry fails, recording its error in place of its result")
+	flags.StringVar(&opts.errorLog, "error-log", "", "path to write the outcome of each batch entry to as JSON lines, requires -keep-going")
+	flags.BoolVar(&opts.noDedup, "no-dedup", false, "submit identical batch entries separately rather than once")
+	flags.DurationVar(&opts.watch, "watch", 0, "re-run the query on the given interval, e.g. 30s, until interrupted")
+	flags.BoolVar(&opts.clear, "clear", false, "clear the screen between runs of -watch")
+	flags.BoolVar(&opts.checkUpdate, "check-update", false, "check whether a newer release is available, printing a notice to stderr")
+	flags.BoolVar(&opts.listEndpoints, "list-endpoints", false, "print the known endpoints along with their default fields")
+	flags.BoolVar(&opts.dumpSchema, "dump-schema", false, "print the built-in knowledge of the endpoints as JSON: their fields, default fields, enum labels and deprecated fields, along with the endpoint aliases and timestamp fields")
+	flags.StringVar(&opts.suggestFields, "suggest-fields", "", "print the fields of the endpoint observed by earlier fields * queries")
+	flags.BoolVar(&opts.smoke, "smoke", false, "run a minimal query against the games, platforms and genres endpoints, reporting whether each passed")
+	flags.StringVar(&opts.crossRef, "cross-ref", "", "print the IDs on the external service of the game with the id given as the argument, e.g. -cross-ref steam 1942 for its Steam app ID")
+	flags.StringVar(&opts.gameCompanies, "game-companies", "", "print the game with the given id along with the names of its developers and publishers")
+	flags.BoolVar(&opts.ci, "ci", false, fmt.Sprintf("run with the defaults for CI, as when %s=true: no color, banner or pager, and errors as JSON", CI_ENV_VAR))
+	flags.StringVar(&opts.errorFormat, "error-format", ERROR_FORMAT_TEXT, "format of errors printed to stderr: text or json")
+	flags.StringVar(&opts.color, "color", COLOR_AUTO, "whether to color the output: auto, only when stdout is a terminal and NO_COLOR is unset, always or never")
+	flags.BoolVar(&opts.pager, "pager", false, "page the output through $PAGER, or less, even when stdout isn't a terminal")
+	flags.BoolVar(&opts.noPager, "no-pager", false, "never page the output, which by default is paged when stdout is a terminal")
+	flags.BoolVar(&opts.flatten, "flatten", false, "flatten nested objects in the results into dotted keys, e.g. genres.0.name")
+	flags.StringVar(&opts.completion, "completion", "", "print the completion script for the given shell: bash, zsh or fish")
+	flags.StringVar(&opts.format, "format", FORMAT_JSON, "output format of the results: json, csv or tsv")
+	flags.BoolVar(&opts.single, "single", false, "print the only result as a bare object rather than an array, failing unless exactly one is returned")
+	flags.IntVar(&opts.head, "head", 0, "print only the first N results, fetching them as usual unlike -limit")
+	flags.IntVar(&opts.tail, "tail", 0, "print only the last N results, fetching them as usual unlike -limit")
+	flags.BoolVar(&opts.validateJSON, "validate-json", false, "exit unsuccessfully unless the response is valid JSON, e.g. rather than an HTML error page from a proxy, whatever the format")
+	flags.StringVar(&opts.schema, "schema", "", "path of a JSON Schema to validate each record against, exiting unsuccessfully with the violations before printing the results")
+	flags.StringVar(&opts.dedupBy, "dedup-by", "", "remove records sharing the value of the field with an earlier record, e.g. id for duplicates at page boundaries with -all")
+	flags.StringVar(&opts.rename, "rename", "", "comma separated old:new pairs of fields to rename in each record, e.g. first_release_date:released, or the flattened keys of nested fields with -flatten")
+	flags.StringVar(&opts.mergeWithFile, "merge-with-file", "", "path of a JSON array of local records to left join the results with on -merge-key, adding their fields, e.g. private annotations")
+	flags.StringVar(&opts.mergeKey, "merge-key", DEFAULT_MERGE_KEY, "field joining the results with the records of -merge-with-file")
+	flags.BoolVar(&opts.mergeOverride, "merge-override", false, "replace the fields of the results with those of the records of -merge-with-file, rather than only adding missing fields")
+	flags.BoolVar(&opts.normalizeStrings, "normalize-strings", false, "add lowercased, accent-stripped variants of the -normalize-fields to the results, e.g. name_normalized")
+	flags.StringVar(&opts.normalizeFields, "normalize-fields", DEFAULT_NORMALIZE_FIELDS, "comma separated string fields normalized by -normalize-strings, at any level of the results")
+	flags.BoolVar(&opts.humanizeDates, "humanize-dates", false, "render the unix timestamps of the results as dates, e.g. first_release_date and updated_at")
+	flags.StringVar(&opts.dateLayout, "date-layout", DEFAULT_DATE_LAYOUT, "Go time layout the dates of -humanize-dates are rendered in, e.g. 02.01.2006 or \"Jan 2, 2006\"")
+	flags.StringVar(&opts.dateTZ, "date-tz", DEFAULT_DATE_TZ, "timezone the dates of -humanize-dates are rendered in, e.g. Europe/Berlin or Local")
+	flags.BoolVar(&opts.withImageURLs, "with-image-urls", false, "add the resolved URL of each image_id in the results alongside it as image_url, e.g. for artworks, screenshots and covers")
+	flags.StringVar(&opts.imageSize, "image-size", DEFAULT_IMAGE_SIZE, "size of the images linked by -with-image-urls, e.g. cover_big, screenshot_med or 1080p")
+	flags.BoolVar(&opts.multiqueryByName, "multiquery-by-name", false, "print the results of multiqueries as an object keyed by the names of their sub-queries")
+	flags.StringVar(&opts.pointer, "pointer", "", "RFC 6901 JSON Pointer to the part of the results to print, e.g. /0/genres/1/name")
+	flags.BoolVar(&opts.stripNulls, "strip-nulls", false, "remove keys with null values from every object in the results")
+	flags.BoolVar(&opts.stripEmpty, "strip-empty", false, "remove keys with empty strings, arrays or objects as values too, requires -strip-nulls")
+	flags.BoolVar(&opts.sortKeys, "sort-keys", false, "re-serialize the JSON result with the keys of every object sorted alphabetically")
+	flags.BoolVar(&opts.pretty, "pretty", false, "re-serialize the JSON result indented")
+	flags.BoolVar(&opts.compact, "compact", false, "re-serialize the JSON result without whitespace")
+	flags.BoolVar(&opts.decodeEnums, "decode-enums", false, "add labels for known enum values alongside them, e.g. category_label \"main_game\" for category 0")
+	flags.BoolVar(&opts.decodeEnumsInPlace, "decode-enums-inplace", false, "replace known enum values with their labels")
+	flags.StringVar(&opts.countDistinct, "count-distinct", "", "print a histogram of the distinct values of the field across the results, e.g. platforms.name")
+	flags.StringVar(&opts.summarize, "summarize", "", "print the count, min, max, mean and median of the numeric field across the results, or its most frequent values, e.g. rating")
+	flags.StringVar(&opts.sqlite, "sqlite", "", "path of a SQLite database to write the flattened results to, in a table named after the endpoint upserted by id")
+	flags.StringVar(&opts.formatter, "formatter", "", "external command formatting the output, e.g. ./myfmt, which reads the JSON results from stdin and prints them formatted to stdout, failing with a non-zero exit")
+	flags.StringVar(&opts.pipe, "pipe", "", "pipe the output through the external command, e.g. 'jq .[0]', printing what it prints")
+	flags.StringVar(&opts.assertCount, "assert-count", "", "count the matching records and exit unsuccessfully unless the count satisfies the expression, e.g. '>100', '=5' or '<10'")
+	flags.DurationVar(&opts.maxAge, "max-age", 0, "exit unsuccessfully unless the newest updated_at of the results is within the duration, e.g. 24h")
+	flags.DurationVar(&opts.cache, "cache", 0, "serve responses from the cache for the duration, e.g. 1h, fetching and caching them once expired")
+	flags.BoolVar(&opts.cacheClear, "cache-clear", false, "remove every cached response")
+	flags.StringVar(&opts.cacheClearEndpoint, "cache-clear-endpoint", "", "remove the cached responses of the endpoint, e.g. games")
+	flags.BoolVar(&opts.cacheStats, "cache-stats", false, "print the number and size of the cached responses, along with the number for each endpoint")
+	flags.BoolVar(&opts.swr, "swr", false, "print an expired cached response straight away, then refresh the cache before exiting, requires -cache")
+	flags.BoolVar(&opts.stream, "stream", false, "print each record as a line of NDJSON as it's decoded, without the banner or holding the whole response in memory")
+	flags.BoolVar(&opts.noTrailingNewline, "no-trailing-newline", false, "omit the newline otherwise ending the output")
+	flags.BoolVar(&opts.withMeta, "with-meta", false, "wrap the results in an envelope recording the endpoint, query, count and fetch time")
+	flags.StringVar(&opts.diff, "diff", "", "path to a JSON snapshot to diff the results against, printing added, removed and changed records by id")
+	flags.StringVar(&opts.diffWith, "diff-with", "", "path to a second JSON snapshot to diff against -diff instead of querying the live database")
+	flags.StringVar(&opts.onlyChanged, "only-changed", "", "path to a JSON snapshot of a previous run, printing only the records new or changed since, by updated_at or else by their fields")
+	flags.BoolVar(&opts.updateSnapshot, "update-snapshot", false, "replace the -only-changed snapshot with the full results, so the next run prints the changes since this one")
+
+	// Default flags from the environment are parsed first so that the command line overrides them.
+	defaultArgs, err := splitArgs(os.Getenv(DEFAULT_OPTIONS_ENV_VAR))
+	if err == nil {
+		err = flags.Parse(defaultArgs)
+	}
+	if err == nil && flags.NArg() > 0 {
+		err = fmt.Errorf("only flags are allowed, found %q", flags.Arg(0))
+	}
+	if err != nil && err != flag.ErrHelp {
+		fmt.Fprintf(flags.Output(), "invalid %s: %s\n", DEFAULT_OPTIONS_ENV_VAR, err.Error())
+		return nil, err
+	}
+
+	err = flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.args = flags.Args()
+	return opts, nil
+}
+
+// splitArgs splits a string into arguments on whitespace, honouring single and double quotes
+// and backslash escapes like a shell.
+func splitArgs(s string) ([]string, error) {
+	args := []string{}
+	current := strings.Builder{}
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// validateOptions validates the values of the parsed options.
+func validateOptions(opts *options) error {
+	err := validateFormat(opts.format)
+	if err != nil {
+		return err
+	}
+	err = validateColor(opts.color)
+	if err != nil {
+		return err
+	}
+	err = validateErrorFormat(opts.errorFormat)
+	if err != nil {
+		return err
+	}
+	err = validateImageSize(opts.imageSize)
+	if err != nil {
+		return err
+	}
+	if opts.rename != "" {
+		if _, err := parseRenames(opts.rename); err != nil {
+			return fmt.Errorf("invalid -rename: %s", err.Error())
+		}
+	}
+	if opts.schema != "" {
+		if _, err := compileSchema(opts.schema); err != nil {
+			return err
+		}
+	}
+	if (isFlagSet("merge-key") || opts.mergeOverride) && opts.mergeWithFile == "" {
+		return errors.New("-merge-key and -merge-override require -merge-with-file")
+	}
+	if opts.mergeWithFile != "" && opts.mergeKey == "" {
+		return errors.New("-merge-key must not be empty")
+	}
+	if opts.crossRef != "" {
+		if err := validateCrossRefSource(opts.crossRef); err != nil {
+			return err
+		}
+	}
+	if (isFlagSet("date-layout") || isFlagSet("date-tz")) && !opts.humanizeDates {
+		return errors.New("-date-layout and -date-tz require -humanize-dates")
+	}
+	if opts.humanizeDates {
+		if _, err := parseDateLocation(opts.dateLayout, opts.dateTZ); err != nil {
+			return err
+		}
+	}
+	err = validateMacroNames(opts.macros)
+	if err != nil {
+		return err
+	}
+	if opts.fieldsExclude != "" && opts.fields != "" {
+		return errors.New("-fields-exclude selects every field, it can't be combined with -fields")
+	}
+	err = validateSafeMode(opts)
+	if err != nil {
+		return err
+	}
+	if opts.withMeta && opts.format != FORMAT_JSON {
+		return fmt.Errorf("-with-meta requires the %s format", FORMAT_JSON)
+	}
+	if opts.pager && (opts.noPager || opts.watch > 0) {
+		return errors.New("-pager can't be combined with -no-pager or -watch")
+	}
+	if opts.head < 0 || opts.tail < 0 {
+		return errors.New("-head and -tail must not be negative")
+	}
+	if opts.head > 0 && opts.tail > 0 {
+		return errors.New("-head and -tail are mutually exclusive")
+	}
+	if _, err := parsePointer(opts.pointer); err != nil {
+		return fmt.Errorf("invalid -pointer: %s", err.Error())
+	}
+	if opts.stripEmpty && !opts.stripNulls {
+		return errors.New("-strip-empty requires -strip-nulls")
+	}
+	if opts.pretty && opts.compact {
+		return errors.New("-pretty and -compact are mutually exclusive")
+	}
+	if (opts.sortKeys || opts.pretty || opts.compact) && opts.format != FORMAT_JSON {
+		return fmt.Errorf("-sort-keys, -pretty and -compact require the %s format", FORMAT_JSON)
+	}
+	if opts.countDistinct != "" && (opts.withMeta || opts.batch != "" || opts.format != FORMAT_JSON) {
+		return errors.New("-count-distinct can't be combined with -with-meta, -batch or -format")
+	}
+	if opts.formatter != "" && (opts.format != FORMAT_JSON || opts.countDistinct != "" || opts.summarize != "" || opts.stream) {
+		return errors.New("-formatter reads JSON, it can't be combined with -format, -count-distinct, -summarize or -stream")
+	}
+	if opts.summarize != "" && (opts.countDistinct != "" || opts.withMeta || opts.batch != "" || opts.format != FORMAT_JSON) {
+		return errors.New("-summarize can't be combined with -count-distinct, -with-meta, -batch or -format")
+	}
+	if opts.maxIdleConns < 0 || opts.maxIdleConnsPerHost < 0 || opts.idleConnTimeout < 0 {
+		return errors.New("-max-idle-conns, -max-idle-conns-per-host and -idle-conn-timeout must not be negative")
+	}
+	if opts.dialTimeout < 0 || opts.tlsHandshakeTimeout < 0 || opts.responseHeaderTimeout < 0 || opts.requestTimeout < 0 {
+		return errors.New("-dial-timeout, -tls-handshake-timeout, -response-header-timeout and -request-timeout must not be negative")
+	}
+	if opts.idsFile != "" && (opts.all || opts.since != "" || opts.batch != "") {
+		return errors.New("-ids-file can't be combined with -all, -since or -batch")
+	}
+	if opts.interactiveAuth && (opts.accessToken != "" || opts.multiCredentials) {
+		return errors.New("-interactive-auth can't be combined with -access-token or -multi-credentials")
+	}
+	if opts.maxAge < 0 {
+		return errors.New("-max-age must not be negative")
+	}
+	if opts.maxAge > 0 && (opts.batch != "" || opts.watch > 0 || opts.stream || opts.assertCount != "") {
+		return errors.New("-max-age can't be combined with -batch, -watch, -stream or -assert-count")
+	}
+	if opts.updateSnapshot && opts.onlyChanged == "" {
+		return errors.New("-update-snapshot requires -only-changed")
+	}
+	if opts.onlyChanged != "" && (opts.diff != "" || opts.batch != "" || opts.watch > 0) {
+		return errors.New("-only-changed can't be combined with -diff, -batch or -watch")
+	}
+	if opts.orderByInput && opts.idsFile == "" {
+		return errors.New("-order-output-by-input requires -ids-file")
+	}
+	if opts.missingIDPlaceholder != "" && (!opts.orderByInput || !json.Valid([]byte(opts.missingIDPlaceholder))) {
+		return errors.New("-missing-id-placeholder must be valid JSON and requires -order-output-by-input")
+	}
+	if opts.stream && (opts.needsProcessing() || opts.all || opts.since != "" || opts.idsFile != "" || opts.batch != "" ||
+		opts.watch > 0 || opts.diff != "" || opts.onlyChanged != "" || opts.sqlite != "" || opts.pipe != "") {
+		return errors.New("-stream prints the records as they arrive, it can't be combined with post-processing, paging, -ids-file, -batch, -watch, -diff, -only-changed, -sqlite or -pipe")
+	}
+	if opts.retries < 0 || opts.retryDelay < 0 || opts.retryBudget < 0 {
+		return errors.New("-retries, -retry-delay and -retry-budget must not be negative")
+	}
+	if opts.retryOnEmpty < 0 || opts.retryOnEmptyDelay < 0 {
+		return errors.New("-retry-on-empty and -retry-on-empty-delay must not be negative")
+	}
+	if opts.cacheClear && opts.cacheClearEndpoint != "" {
+		return errors.New("-cache-clear and -cache-clear-endpoint are mutually exclusive")
+	}
+	if opts.cache < 0 {
+		return errors.New("-cache must not be negative")
+	}
+	if opts.swr && opts.cache == 0 {
+		return errors.New("-swr requires -cache")
+	}
+	if opts.swr && (opts.assertCount != "" || opts.stream || opts.watch > 0) {
+		return errors.New("-swr can't be combined with -assert-count, -stream or -watch")
+	}
+	if opts.cache > 0 && opts.idsFile != "" {
+		return errors.New("-cache can't be combined with -ids-file")
+	}
+	if opts.defaultLimit < 0 || opts.defaultLimit > MAX_QUERY_LIMIT {
+		return fmt.Errorf("-default-limit must be between 0 and %d", MAX_QUERY_LIMIT)
+	}
+	if opts.assertCount != "" {
+		_, err = parseCountAssertion(opts.assertCount)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.parallelPages && !opts.all && opts.since == "" {
+		return errors.New("-parallel-pages requires -all or -since")
+	}
+	if opts.strict && !opts.lint {
+		return errors.New("-strict requires -lint")
+	}
+	if opts.batch != "" && opts.format != FORMAT_JSON {
+		return fmt.Errorf("-batch requires the %s format", FORMAT_JSON)
+	}
+	splitting := opts.splitSize > 0 || opts.splitBytes != ""
+	if opts.outputDir != "" && opts.batch == "" && !splitting {
+		return fmt.Errorf("-output-dir requires -batch, -split-size or -split-bytes")
+	}
+	if opts.splitSize < 0 {
+		return errors.New("-split-size must not be negative")
+	}
+	if opts.splitBytes != "" {
+		if _, err := parseByteSize(opts.splitBytes); err != nil {
+			return fmt.Errorf("invalid -split-bytes: %s", err.Error())
+		}
+	}
+	if splitting && (opts.outputDir == "" || opts.batch != "" || opts.sqlite != "" || opts.diff != "" || opts.watch > 0 || opts.stream) {
+		return errors.New("-split-size and -split-bytes require -output-dir, and can't be combined with -batch, -sqlite, -diff, -watch or -stream")
+	}
+	if splitting && opts.replacesRecords() {
+		return fmt.Errorf("-split-size and -split-bytes write records, so require the %s format and can't be combined with -with-meta, -single, -pointer, -count-distinct or -summarize", FORMAT_JSON)
+	}
+	if opts.sqlite != "" && opts.replacesRecords() {
+		return fmt.Errorf("-sqlite writes records, so requires the %s format and can't be combined with -with-meta, -single, -pointer, -count-distinct or -summarize", FORMAT_JSON)
+	}
+	if opts.keepGoing && opts.batch == "" {
+		return errors.New("-keep-going requires -batch")
+	}
+	if opts.errorLog != "" && !opts.keepGoing {
+		return errors.New("-error-log requires -keep-going")
+	}
+	return nil
+}
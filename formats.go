@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// The supported output formats.
+	FORMAT_JSON = "json"
+	FORMAT_CSV  = "csv"
+	FORMAT_TSV  = "tsv"
+)
+
+// tsvEscaper escapes the characters that would break the rows or columns of TSV output.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// validateFormat validates that the output format is supported.
+func validateFormat(format string) error {
+	switch format {
+	case FORMAT_JSON, FORMAT_CSV, FORMAT_TSV:
+		return nil
+	default:
+		return fmt.Errorf("unsupported format %q, expected one of %s, %s or %s", format, FORMAT_JSON, FORMAT_CSV, FORMAT_TSV)
+	}
+}
+
+// formatResult formats the decoded result in the given output format, ordering the columns of
+// tables by the fields in the order they were selected.
+func formatResult(format string, result interface{}, fields []string) (string, error) {
+	switch format {
+	case FORMAT_CSV:
+		return formatCSV(result, fields)
+	case FORMAT_TSV:
+		return formatTSV(result, fields)
+	default:
+		return encodeJSON(result)
+	}
+}
+
+// tabulate derives the columns and rows of the records in the result, with one column per key
+// found across the records, ordered by the fields.
+func tabulate(result interface{}, fields []string) ([]string, [][]string, error) {
+	records, ok := result.([]interface{})
+	if !ok {
+		records = []interface{}{result}
+	}
+
+	// Collect the union of the keys in a stable order.
+	seen := map[string]bool{}
+	columns := []string{}
+	for i, record := range records {
+		object, ok := record.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("record at index %d is not an object", i)
+		}
+		for key := range object {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	orderColumns(columns, fields)
+
+	rows := [][]string{}
+	for _, record := range records {
+		object := record.(map[string]interface{})
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			cell, err := formatCell(object[column])
+			if err != nil {
+				return nil, nil, err
+			}
+			row[i] = cell
+		}
+		rows = append(rows, row)
+	}
+
+	return columns, rows, nil
+}
+
+// orderColumns orders the columns by the first of the fields each belongs to, e.g. genres.0.name
+// to genres.name or genres, followed by the columns of no field, each alphabetically but for the
+// indexes of arrays, which are ordered numerically.
+func orderColumns(columns []string, fields []string) {
+	rank := func(column string) int {
+		path := columnFieldPath(column)
+		for i, field := range fields {
+			field = strings.TrimSuffix(field, ".*")
+			if path == field || strings.HasPrefix(path, field+".") {
+				return i
+			}
+		}
+		return len(fields)
+	}
+
+	sort.Slice(columns, func(i, j int) bool {
+		rankI, rankJ := rank(columns[i]), rank(columns[j])
+		if rankI != rankJ {
+			return rankI < rankJ
+		}
+		return lessColumn(columns[i], columns[j])
+	})
+}
+
+// lessColumn orders the columns by their dotted segments, comparing segments that are both
+// indexes numerically, so that genres.2 is ordered before genres.10.
+func lessColumn(a string, b string) bool {
+	segmentsA, segmentsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		segmentA, segmentB := segmentsA[i], segmentsB[i]
+		if segmentA == segmentB {
+			continue
+		}
+		indexA, errA := strconv.Atoi(segmentA)
+		indexB, errB := strconv.Atoi(segmentB)
+		if errA == nil && errB == nil {
+			return indexA < indexB
+		}
+		return segmentA < segmentB
+	}
+	return len(segmentsA) < len(segmentsB)
+}
+
+// columnFieldPath returns the path of the field a column holds, without the indexes of arrays
+// introduced by flattening, e.g. genres.name for genres.0.name.
+func columnFieldPath(column string) string {
+	segments := []string{}
+	for _, segment := range strings.Split(column, ".") {
+		if _, err := strconv.Atoi(segment); err != nil {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// formatCell formats a single value as a table cell, encoding nested values as compact JSON.
+func formatCell(value interface{}) (string, error) {
+	switch cell := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return cell, nil
+	case json.Number:
+		return cell.String(), nil
+	case bool:
+		return fmt.Sprint(cell), nil
+	default:
+		cellBytes, err := json.Marshal(cell)
+		if err != nil {
+			return "", err
+		}
+		return string(cellBytes), nil
+	}
+}
+
+// formatCSV formats the records in the result as CSV with a header row.
+func formatCSV(result interface{}, fields []string) (string, error) {
+	columns, rows, err := tabulate(result, fields)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+	err = writer.Write(columns)
+	if err != nil {
+		return "", err
+	}
+	err = writer.WriteAll(rows)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// formatTSV formats the records in the result as TSV with a header row, escaping embedded
+// backslashes, tabs and newlines.
+func formatTSV(result interface{}, fields []string) (string, error) {
+	columns, rows, err := tabulate(result, fields)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	for _, row := range append([][]string{columns}, rows...) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = tsvEscaper.Replace(cell)
+		}
+		lines = append(lines, strings.Join(cells, "\t"))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
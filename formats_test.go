@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderColumns(t *testing.T) {
+	cases := []struct {
+		name     string
+		columns  []string
+		fields   []string
+		expected []string
+	}{
+		{
+			"indexes numerically",
+			[]string{"genres.10", "genres.2", "genres.1", "id"},
+			[]string{"id", "genres"},
+			[]string{"id", "genres.1", "genres.2", "genres.10"},
+		},
+		{
+			"nested indexes",
+			[]string{"genres.10.name", "genres.2.name", "genres.2.id", "name"},
+			[]string{"name", "genres.*"},
+			[]string{"name", "genres.2.id", "genres.2.name", "genres.10.name"},
+		},
+		{
+			"names alphabetically",
+			[]string{"summary", "rating", "id", "cover.url", "cover"},
+			nil,
+			[]string{"cover", "cover.url", "id", "rating", "summary"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			columns := append([]string{}, c.columns...)
+			orderColumns(columns, c.fields)
+			if !reflect.DeepEqual(columns, c.expected) {
+				t.Errorf("expected %q, got %q", c.expected, columns)
+			}
+		})
+	}
+}
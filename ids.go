@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// The fields fetched for the IDs when no query is given.
+	DEFAULT_IDS_QUERY = "fields *;"
+)
+
+// readIDs reads the newline separated IDs of the file, skipping blank lines, # comments and
+// repeated IDs.
+func readIDs(path string) ([]int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ids := []int64{}
+	seen := map[int64]bool{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		id, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid ID %q", line, text)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// fetchByIDs fetches the records with the IDs of the -ids-file in chunks of as many IDs as fit in
+// a response, narrowing the query to each chunk and merging the chunks in order.
+func fetchByIDs(ctx context.Context, opts *options, databaseClient *DatabaseClient, endpoint string, query string) (string, error) {
+	if endpoint == MULTIQUERY_ENDPOINT || strings.HasSuffix(endpoint, COUNT_ENDPOINT_SUFFIX) {
+		return "", fmt.Errorf("-ids-file is not supported for the %s endpoint", endpoint)
+	}
+	ids, err := readIDs(opts.idsFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the IDs file: %s", err.Error())
+	}
+
+	merged := []json.RawMessage{}
+	for start := 0; start < len(ids); start += MAX_QUERY_LIMIT {
+		end := start + MAX_QUERY_LIMIT
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		q, err := parseQuery(query)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse query: %s", err.Error())
+		}
+		chunk := make([]string, end-start)
+		for i, id := range ids[start:end] {
+			chunk[i] = strconv.FormatInt(id, 10)
+		}
+		q.addWhere(fmt.Sprintf("id = (%s)", strings.Join(chunk, ",")))
+		q.set("limit", strconv.Itoa(len(chunk)))
+
+		result, err := databaseClient.QueryContext(ctx, endpoint, q.String())
+		if err != nil && len(merged) > 0 {
+			return incompleteResult(merged, err)
+		}
+		if err != nil {
+			return "", err
+		}
+		records := []json.RawMessage{}
+		err = json.Unmarshal([]byte(result), &records)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode the records of IDs %d to %d: %s", start+1, end, err.Error())
+		}
+		merged = append(merged, records...)
+	}
+
+	if opts.orderByInput {
+		merged, err = orderByIDs(merged, ids, opts.missingIDPlaceholder)
+		if err != nil {
+			return "", err
+		}
+	}
+	return encodeJSON(merged)
+}
+
+// orderByIDs orders the records to match the order of the IDs, standing in the placeholder for
+// the records of missing IDs, or skipping them when the placeholder is empty.
+func orderByIDs(records []json.RawMessage, ids []int64, placeholder string) ([]json.RawMessage, error) {
+	byID := map[int64]json.RawMessage{}
+	for i, record := range records {
+		identified := &struct {
+			ID *int64 `json:"id"`
+		}{}
+		err := json.Unmarshal(record, identified)
+		if err != nil || identified.ID == nil {
+			return nil, fmt.Errorf("record at index %d has no id to order by", i)
+		}
+		byID[*identified.ID] = record
+	}
+
+	ordered := []json.RawMessage{}
+	for _, id := range ids {
+		record, ok := byID[id]
+		if ok {
+			ordered = append(ordered, record)
+		} else if placeholder != "" {
+			ordered = append(ordered, json.RawMessage(placeholder))
+		}
+	}
+	return ordered, nil
+}
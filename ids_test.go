@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestPrepareQueryRejectsOffsetsWithIDsFile(t *testing.T) {
+	cases := []struct {
+		name  string
+		args  []string
+		query string
+		valid bool
+	}{
+		{"without offset", []string{"-ids-file", "ids.txt"}, "fields name;", true},
+		{"without query", []string{"-ids-file", "ids.txt"}, "", true},
+		{"with offset", []string{"-ids-file", "ids.txt"}, "fields name; offset 10;", false},
+		{"with base query offset", []string{"-ids-file", "ids.txt", "-base-query", "offset 10;"}, "fields name;", false},
+		{"offset without ids file", nil, "fields name; offset 10;", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := prepareQuery(parseTestOptions(t, c.args...), "games", c.query)
+			if (err == nil) != c.valid {
+				t.Errorf("expected %q valid %t, got %v", c.query, c.valid, err)
+			}
+		})
+	}
+}
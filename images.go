@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Constants for resolving the URLs of IGDB images from their image IDs.
+	IGDB_IMAGE_BASE_URL = "https://images.igdb.com/igdb/image/upload"
+	DEFAULT_IMAGE_SIZE  = "original"
+
+	// Key of the resolved URL added alongside each image_id by -with-image-urls.
+	IMAGE_URL_KEY = "image_url"
+)
+
+// imageSizes are the sizes the IGDB serves images in.
+var imageSizes = []string{
+	"cover_small", "cover_big", "screenshot_med", "screenshot_big", "screenshot_huge", "logo_med", "thumb",
+	"micro", "720p", "1080p", DEFAULT_IMAGE_SIZE,
+}
+
+// ImageURL returns the URL of the image in the given size, e.g. cover_big or 1080p.
+func ImageURL(imageID string, size string) string {
+	return fmt.Sprintf("%s/t_%s/%s.jpg", IGDB_IMAGE_BASE_URL, size, imageID)
+}
+
+// validateImageSize validates that the IGDB serves images in the size.
+func validateImageSize(size string) error {
+	for _, known := range imageSizes {
+		if size == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported image size %q, expected one of %s", size, strings.Join(imageSizes, ", "))
+}
+
+// addImageURLs adds the resolved URL alongside the image_id of every object at every level, e.g.
+// of artworks and screenshots or the covers expanded within games.
+func addImageURLs(value interface{}, size string) interface{} {
+	switch nested := value.(type) {
+	case []interface{}:
+		for _, element := range nested {
+			addImageURLs(element, size)
+		}
+	case map[string]interface{}:
+		for _, nestedValue := range nested {
+			addImageURLs(nestedValue, size)
+		}
+		if imageID, ok := nested["image_id"].(string); ok && imageID != "" {
+			nested[IMAGE_URL_KEY] = ImageURL(imageID, size)
+		}
+	}
+	return value
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+)
+
+// readQuery determines the endpoint and query to submit from the positional arguments,
+// a query file, the system clipboard, a JSON query spec on stdin, or a global search term.
+func readQuery(opts *options) (string, string, error) {
+	// Only one source of the query may be given.
+	sources := 0
+	if len(opts.args) > 1 {
+		sources++
+	}
+	if opts.queryFile != "" {
+		sources++
+	}
+	if opts.queryClipboard {
+		sources++
+	}
+	if opts.queryStdinJSON {
+		sources++
+	}
+	if opts.globalSearch != "" {
+		sources++
+	}
+	if sources > 1 {
+		return "", "", errors.New("the positional query, -query-file, -query-clipboard, -query-stdin-json and -global-search are mutually exclusive")
+	}
+
+	// Global searches query their own endpoint.
+	if opts.globalSearch != "" {
+		if len(opts.args) > 0 {
+			return "", "", errors.New("-global-search queries the search endpoint, no arguments may be given")
+		}
+		return SEARCH_ENDPOINT, globalSearchQuery(opts.globalSearch), nil
+	}
+
+	// The spec names its own endpoint.
+	if opts.queryStdinJSON {
+		if len(opts.args) > 0 {
+			return "", "", errors.New("-query-stdin-json reads the endpoint from the spec, no arguments may be given")
+		}
+		return readQuerySpec(os.Stdin)
+	}
+
+	switch {
+	case len(opts.args) == 1 && sources == 0 && opts.idsFile != "":
+		return opts.args[0], DEFAULT_IDS_QUERY, nil
+	case len(opts.args) == 2:
+		return cleanQuery(opts.args[0], opts.args[1])
+	case len(opts.args) != 1:
+		return "", "", errors.New("an endpoint and query must be provided")
+	case opts.queryFile != "":
+		queryBytes, err := os.ReadFile(opts.queryFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read query file: %s", err.Error())
+		}
+		return cleanQuery(opts.args[0], string(queryBytes))
+	case opts.queryClipboard:
+		if clipboard.Unsupported {
+			return "", "", errors.New("no clipboard is available on this system")
+		}
+		query, err := clipboard.ReadAll()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read the clipboard: %s", err.Error())
+		}
+		return cleanQuery(opts.args[0], query)
+	default:
+		return "", "", errors.New("a query must be provided")
+	}
+}
+
+// cleanQuery trims the query of surrounding whitespace, a byte order mark and the carriage returns
+// of Windows line endings, e.g. from here-docs and files written on Windows, leaving the lines
+// within it as they are.
+func cleanQuery(endpoint string, query string) (string, string, error) {
+	query = strings.TrimPrefix(query, "\ufeff")
+	query = strings.ReplaceAll(query, "\r\n", "\n")
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", "", errors.New("the query is empty")
+	}
+	return endpoint, query, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestCleanQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "surrounding whitespace",
+			query: "\n\t  fields name; limit 5;  \n\n",
+			want:  "fields name; limit 5;",
+		},
+		{
+			name:  "crlf line endings",
+			query: "fields name,\r\n  rating;\r\nlimit 5;\r\n",
+			want:  "fields name,\n  rating;\nlimit 5;",
+		},
+		{
+			name:  "blank lines between clauses",
+			query: "fields name;\n\n\nwhere rating > 80;\n\n",
+			want:  "fields name;\n\n\nwhere rating > 80;",
+		},
+		{
+			name:  "whitespace within strings",
+			query: "  search \"  The   Legend\tof\nZelda  \";  ",
+			want:  "search \"  The   Legend\tof\nZelda  \";",
+		},
+		{
+			name:  "byte order mark",
+			query: "\ufefffields name;\n",
+			want:  "fields name;",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			endpoint, query, err := cleanQuery("games", test.query)
+			if err != nil {
+				t.Fatalf("failed to clean the query: %s", err.Error())
+			}
+			if endpoint != "games" {
+				t.Errorf("expected the endpoint games, got %q", endpoint)
+			}
+			if query != test.want {
+				t.Errorf("expected %q, got %q", test.want, query)
+			}
+		})
+	}
+}
+
+func TestCleanQueryRejectsBlankQueries(t *testing.T) {
+	for _, query := range []string{"", " \n\t", "\r\n\r\n"} {
+		_, _, err := cleanQuery("games", query)
+		if err == nil {
+			t.Errorf("expected an error for the blank query %q", query)
+		}
+	}
+}
+
+func TestReadQueryCleansMultilineArguments(t *testing.T) {
+	heredoc := "\nfields name,\n       rating;\nwhere name = \"Half  Life\";\n"
+	endpoint, query, err := readQuery(&options{args: []string{"games", heredoc}})
+	if err != nil {
+		t.Fatalf("failed to read the query: %s", err.Error())
+	}
+	want := "fields name,\n       rating;\nwhere name = \"Half  Life\";"
+	if endpoint != "games" || query != want {
+		t.Errorf("expected games and %q, got %s and %q", want, endpoint, query)
+	}
+}
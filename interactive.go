@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// Constants for authorizing interactively with the Twitch device code flow, which requires
+	// only the client ID of a public application, no client secret.
+	TWITCH_DEVICE_URL                = "https://id.twitch.tv/oauth2/device"
+	TWITCH_DEVICE_CODE_GRANT_TYPE    = "urn:ietf:params:oauth:grant-type:device_code"
+	TWITCH_AUTHORIZATION_PENDING     = "authorization_pending"
+	TWITCH_SLOW_DOWN                 = "slow_down"
+	DEFAULT_DEVICE_POLL_INTERVAL     = 5 * time.Second
+	INTERACTIVE_AUTH_TOKEN_FILE_NAME = "tokens.json"
+
+	// Polling asked to slow down waits this much longer between polls from then on, per RFC 8628.
+	DEVICE_POLL_SLOW_DOWN = 5 * time.Second
+
+	// Cached tokens expiring within the margin are treated as expired, so they don't lapse mid-run.
+	TOKEN_EXPIRY_MARGIN = time.Minute
+)
+
+// twitchDeviceResponse represents the JSON response body starting the Twitch device code flow.
+type twitchDeviceResponse struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int32  `json:"expires_in"`
+	Interval        int32  `json:"interval"`
+}
+
+// devicePollState is the outcome of polling for the token of a device code.
+type devicePollState int
+
+const (
+	devicePollAuthorized devicePollState = iota
+	devicePollPending
+	devicePollSlowDown
+)
+
+// cachedToken is an interactively authorized token cached until it expires.
+type cachedToken struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// tokenCachePath returns the path of the cache of interactively authorized tokens.
+func tokenCachePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, INTERACTIVE_AUTH_TOKEN_FILE_NAME), nil
+}
+
+// loadCachedTokens reads the cached tokens by client ID, or none when nothing is cached.
+func loadCachedTokens() (map[string]cachedToken, error) {
+	tokens := map[string]cachedToken{}
+	path, err := tokenCachePath()
+	if err != nil {
+		return tokens, err
+	}
+	cached, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return tokens, err
+	}
+	err = json.Unmarshal(cached, &tokens)
+	if err != nil {
+		return map[string]cachedToken{}, err
+	}
+	return tokens, nil
+}
+
+// storeCachedToken caches the token of the client ID, readable only by the user.
+func storeCachedToken(clientID string, token cachedToken) error {
+	tokens, _ := loadCachedTokens()
+	tokens[clientID] = token
+
+	path, err := tokenCachePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	cached, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, cached, 0600)
+}
+
+// getInteractiveAuthToken returns the cached token of the client ID while it's valid, otherwise
+// authorizing a new one in the browser and caching it for later runs.
+func getInteractiveAuthToken(ctx context.Context, httpClient *http.Client, clientID string) (string, error) {
+	tokens, err := loadCachedTokens()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read the token cache with error: %s\n", err.Error())
+	}
+	if token, ok := tokens[clientID]; ok && time.Now().Add(TOKEN_EXPIRY_MARGIN).Before(token.ExpiresAt) {
+		return token.AccessToken, nil
+	}
+
+	auth, err := authorizeDevice(ctx, httpClient, clientID)
+	if err != nil {
+		return "", err
+	}
+	token := cachedToken{
+		AccessToken: auth.AccessToken,
+		ExpiresAt:   time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second).UTC(),
+	}
+	err = storeCachedToken(clientID, token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the token cache with error: %s\n", err.Error())
+	}
+	return token.AccessToken, nil
+}
+
+// authorizeDevice authorizes the client ID with the Twitch device code flow, opening the
+// verification page in the browser and waiting for the user to approve it there, or until the
+// context is done.
+func authorizeDevice(ctx context.Context, httpClient *http.Client, clientID string) (*twitchAuthResponse, error) {
+	form := url.Values{"client_id": {clientID}, "scopes": {""}}
+	resp, err := postForm(ctx, httpClient, TWITCH_DEVICE_URL, form)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to start the device flow, unexpected status %s: %s", resp.Status, string(respBytes))
+	}
+	device := &twitchDeviceResponse{}
+	err = json.Unmarshal(respBytes, device)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "To authorize, visit %s and enter the code %s\n", device.VerificationURI, device.UserCode)
+	err = openBrowser(device.VerificationURI)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open the browser with error: %s\n", err.Error())
+	}
+
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = DEFAULT_DEVICE_POLL_INTERVAL
+	}
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		err = sleepContext(ctx, interval)
+		if err != nil {
+			return nil, err
+		}
+		auth, state, err := pollDeviceToken(ctx, httpClient, clientID, device.DeviceCode)
+		if err != nil {
+			return nil, err
+		}
+		switch state {
+		case devicePollAuthorized:
+			return auth, nil
+		case devicePollSlowDown:
+			interval += DEVICE_POLL_SLOW_DOWN
+		}
+	}
+	return nil, errors.New("the device code expired before it was authorized")
+}
+
+// pollDeviceToken polls for the token of the device code, reporting whether its authorization is
+// still pending or polling should slow down.
+func pollDeviceToken(ctx context.Context, httpClient *http.Client, clientID string, deviceCode string) (*twitchAuthResponse, devicePollState, error) {
+	form := url.Values{
+		"client_id":   {clientID},
+		"scopes":      {""},
+		"device_code": {deviceCode},
+		"grant_type":  {TWITCH_DEVICE_CODE_GRANT_TYPE},
+	}
+	resp, err := postForm(ctx, httpClient, TWITCH_AUTH_URL, form)
+	if err != nil {
+		return nil, devicePollAuthorized, err
+	}
+	defer closeBody(resp)
+
+	if resp.StatusCode == http.StatusBadRequest {
+		respBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, devicePollAuthorized, err
+		}
+		authErr := &twitchAuthError{}
+		if json.Unmarshal(respBytes, authErr) == nil {
+			switch authErr.Message {
+			case TWITCH_AUTHORIZATION_PENDING:
+				return nil, devicePollPending, nil
+			case TWITCH_SLOW_DOWN:
+				return nil, devicePollSlowDown, nil
+			}
+		}
+		return nil, devicePollAuthorized, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(respBytes)))
+	}
+
+	auth, err := parseAuthResponse(resp)
+	return auth, devicePollAuthorized, err
+}
+
+// postForm posts the form to the URL within the context.
+func postForm(ctx context.Context, httpClient *http.Client, target string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(IGDB_CONTENT_TYPE_HEADER, FORM_CONTENT_TYPE)
+	return httpClient.Do(req)
+}
+
+// openBrowser opens the URL in the user's default browser.
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
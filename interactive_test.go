@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPollDeviceToken(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		state  devicePollState
+		fails  bool
+	}{
+		{"authorized", http.StatusOK, `{"access_token":"token","expires_in":3600,"token_type":"bearer"}`, devicePollAuthorized, false},
+		{"pending", http.StatusBadRequest, `{"status":400,"message":"authorization_pending"}`, devicePollPending, false},
+		{"slow down", http.StatusBadRequest, `{"status":400,"message":"slow_down"}`, devicePollSlowDown, false},
+		{"denied", http.StatusBadRequest, `{"status":400,"message":"access_denied"}`, devicePollAuthorized, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var contentType string
+			httpClient, _ := newTestAuthClient(t, func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get(IGDB_CONTENT_TYPE_HEADER)
+				w.WriteHeader(c.status)
+				io.WriteString(w, c.body)
+			})
+
+			_, state, err := pollDeviceToken(context.Background(), httpClient, "client-id", "device-code")
+			if (err != nil) != c.fails {
+				t.Fatalf("expected failure %t, got %v", c.fails, err)
+			}
+			if state != c.state {
+				t.Errorf("expected state %d, got %d", c.state, state)
+			}
+			if contentType != FORM_CONTENT_TYPE {
+				t.Errorf("expected the form content type, got %q", contentType)
+			}
+		})
+	}
+}
+
+func TestPollDeviceTokenStopsWithTheContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	httpClient, _ := newTestAuthClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _, err := pollDeviceToken(ctx, httpClient, "client-id", "device-code")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context's error, got %v", err)
+	}
+}
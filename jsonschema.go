@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const (
+	// The most violations of -schema reported before the rest are summarized.
+	MAX_SCHEMA_VIOLATIONS = 20
+)
+
+// compileSchema compiles the JSON Schema at the path, as well as any it references.
+func compileSchema(path string) (*jsonschema.Schema, error) {
+	schema, err := jsonschema.Compile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile the schema %s: %s", path, err.Error())
+	}
+	return schema, nil
+}
+
+// schemaViolations validates each record of the result against the schema, returning the
+// violations of every invalid record located by its index, id when selected, and field.
+func schemaViolations(schema *jsonschema.Schema, result string) ([]string, error) {
+	decoded, err := decodeJSON(result)
+	if err != nil {
+		return nil, err
+	}
+	records, ok := decoded.([]interface{})
+	if !ok {
+		records = []interface{}{decoded}
+	}
+
+	violations := []string{}
+	for i, record := range records {
+		err := schema.Validate(record)
+		validationErr := &jsonschema.ValidationError{}
+		if errors.As(err, &validationErr) {
+			label := fmt.Sprintf("record %d", i)
+			if object, ok := record.(map[string]interface{}); ok && object["id"] != nil {
+				label = fmt.Sprintf("record %d (id %v)", i, object["id"])
+			}
+			for _, cause := range leafCauses(validationErr) {
+				field := cause.InstanceLocation
+				if field == "" {
+					field = "/"
+				}
+				violations = append(violations, fmt.Sprintf("%s at %s: %s", label, field, cause.Message))
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return violations, nil
+}
+
+// leafCauses returns the most specific causes of the validation error, which locate the fields
+// in violation rather than the records containing them.
+func leafCauses(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	causes := []*jsonschema.ValidationError{}
+	for _, cause := range err.Causes {
+		causes = append(causes, leafCauses(cause)...)
+	}
+	return causes
+}
+
+// checkSchema returns an assertion error reporting the violations unless every record of the
+// result is valid against -schema.
+func checkSchema(opts *options, endpoint string, result string) error {
+	schema, err := compileSchema(opts.schema)
+	if err != nil {
+		handleErr("failed to validate the records", err, BAD_USAGE_EXIT_CODE)
+	}
+	violations, err := schemaViolations(schema, result)
+	if err != nil {
+		handleErr("failed to validate the records", err, INTERNAL_ERROR_EXIT_CODE)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	reported := violations
+	if len(reported) > MAX_SCHEMA_VIOLATIONS {
+		reported = reported[:MAX_SCHEMA_VIOLATIONS]
+	}
+	message := fmt.Sprintf("%d violations of %s by the %s records:\n  %s", len(violations), opts.schema, endpoint, strings.Join(reported, "\n  "))
+	if len(violations) > len(reported) {
+		message += fmt.Sprintf("\n  and %d more", len(violations)-len(reported))
+	}
+	return &assertionError{message}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// knownClauses are the clause keywords of the APIcalypse query language.
+var knownClauses = map[string]bool{"fields": true, "exclude": true, "where": true, "sort": true, "limit": true, "offset": true, "search": true}
+
+// lintQuery statically checks the endpoint and query for common mistakes, returning a warning for each.
+func lintQuery(endpoint string, query string) []string {
+	warnings := []string{}
+	if !isKnownEndpoint(endpoint) {
+		warnings = append(warnings, fmt.Sprintf("unknown endpoint %q", endpoint))
+	}
+	// Subqueries are checked when the multiquery is parsed.
+	if endpoint == MULTIQUERY_ENDPOINT {
+		return warnings
+	}
+
+	trimmed := strings.TrimSpace(query)
+	if trimmed != "" && !strings.HasSuffix(trimmed, ";") {
+		warnings = append(warnings, "the last clause is missing its terminating semicolon")
+	}
+
+	q, err := parseQuery(query)
+	if err != nil {
+		return append(warnings, err.Error())
+	}
+	for _, clause := range q.clauses {
+		if !knownClauses[clause.keyword] {
+			warnings = append(warnings, fmt.Sprintf("unknown clause %q", clause.keyword))
+		}
+	}
+
+	if _, ok := q.get("search"); ok {
+		if _, ok := q.get("sort"); ok {
+			warnings = append(warnings, "search results are ordered by relevance and can't be sorted")
+		}
+	}
+	if value, ok := q.get("limit"); ok {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("invalid limit %q", value))
+		} else if limit > MAX_QUERY_LIMIT {
+			warnings = append(warnings, fmt.Sprintf("limit %d exceeds the maximum of %d", limit, MAX_QUERY_LIMIT))
+		}
+	}
+
+	// Check the fields selected, excluded or sorted by against the schema.
+	for _, keyword := range []string{"fields", "exclude", "sort"} {
+		value, ok := q.get(keyword)
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Split(value, ",") {
+			// Ignore the direction of sorts, e.g. rating desc.
+			words := strings.Fields(field)
+			if len(words) == 0 {
+				continue
+			}
+			field = words[0]
+			known, inSchema := isKnownField(endpoint, field)
+			if !inSchema {
+				break
+			}
+			if !known {
+				warnings = append(warnings, fmt.Sprintf("unknown field %q in the %s clause of %s", field, keyword, endpoint))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// reportLint prints the warnings about the query to stderr, exiting before the query is sent when
+// -strict is set and there are any.
+func reportLint(opts *options, endpoint string, query string) {
+	warnings := lintQuery(endpoint, query)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "lint: %s\n", warning)
+	}
+	if opts.strict && len(warnings) > 0 {
+		handleErr("failed to lint the query", fmt.Errorf("%d warnings under -strict", len(warnings)), BAD_USAGE_EXIT_CODE)
+	}
+}
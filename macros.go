@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// The deepest macros may reference other macros, which stops cycles from expanding forever.
+	MAX_MACRO_DEPTH = 10
+)
+
+var (
+	// macroPattern matches the references to macros, e.g. {recent}, unlike the sets of ids of
+	// APIcalypse, e.g. {48,49}.
+	macroPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+	// relativeTimePattern matches the placeholders of times relative to now, e.g. <now-30d>.
+	relativeTimePattern = regexp.MustCompile(`<now(?:([+-])([0-9]+)([smhdw]))?>`)
+
+	// macroNamePattern matches the valid names of macros.
+	macroNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// relativeTimeUnits are the durations of the units of relative time placeholders.
+var relativeTimeUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// validateMacroNames validates that the names of the configured macros can be referenced.
+func validateMacroNames(macros map[string]string) error {
+	for name := range macros {
+		if !macroNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid macro name %q, expected letters, digits and underscores", name)
+		}
+	}
+	return nil
+}
+
+// expandMacros expands the references to macros outside of string literals, along with those in
+// the macros they expand to, then replaces the relative time placeholders with unix epochs, e.g.
+// <now-30d> with the time 30 days ago.
+func expandMacros(query string, macros map[string]string, now time.Time) (string, error) {
+	expanded := query
+	for depth := 0; len(macros) > 0 && hasMatchOutsideStrings(expanded, macroPattern); depth++ {
+		if depth >= MAX_MACRO_DEPTH {
+			return "", fmt.Errorf("macros nest deeper than %d, check them for cycles", MAX_MACRO_DEPTH)
+		}
+		var err error
+		expanded, err = replaceOutsideStrings(expanded, macroPattern, func(match []string) (string, error) {
+			body, ok := macros[match[1]]
+			if !ok {
+				return "", fmt.Errorf("undefined macro %s", match[0])
+			}
+			return body, nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return replaceOutsideStrings(expanded, relativeTimePattern, func(match []string) (string, error) {
+		if match[1] == "" {
+			return strconv.FormatInt(now.Unix(), 10), nil
+		}
+		amount, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid relative time %s", match[0])
+		}
+		offset := time.Duration(amount) * relativeTimeUnits[match[3]]
+		if match[1] == "-" {
+			offset = -offset
+		}
+		return strconv.FormatInt(now.Add(offset).Unix(), 10), nil
+	})
+}
+
+// hasMatchOutsideStrings reports whether the pattern matches the query outside of string literals.
+func hasMatchOutsideStrings(query string, pattern *regexp.Regexp) bool {
+	found := false
+	replaceOutsideStrings(query, pattern, func(match []string) (string, error) {
+		found = true
+		return match[0], nil
+	})
+	return found
+}
+
+// replaceOutsideStrings replaces the matches of the pattern outside of the string literals of the
+// query with the replacements, stopping at the first failure.
+func replaceOutsideStrings(query string, pattern *regexp.Regexp, replace func(match []string) (string, error)) (string, error) {
+	replaced := &strings.Builder{}
+	var replaceErr error
+	flush := func(segment string) {
+		replaced.WriteString(pattern.ReplaceAllStringFunc(segment, func(match string) string {
+			if replaceErr != nil {
+				return match
+			}
+			replacement, err := replace(pattern.FindStringSubmatch(match))
+			if err != nil {
+				replaceErr = err
+				return match
+			}
+			return replacement
+		}))
+	}
+
+	start := 0
+	inString := false
+	for i := 0; i < len(query); i++ {
+		switch {
+		case inString && query[i] == '\\':
+			i++
+		case query[i] == '"' && !inString:
+			flush(query[start:i])
+			start = i
+			inString = true
+		case query[i] == '"' && inString:
+			replaced.WriteString(query[start : i+1])
+			start = i + 1
+			inString = false
+		}
+	}
+	if inString {
+		replaced.WriteString(query[start:])
+	} else {
+		flush(query[start:])
+	}
+
+	if replaceErr != nil {
+		return "", replaceErr
+	}
+	return replaced.String(), nil
+}
@@ -2,15 +2,20 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 )
 
-// Ideally, the following would be separated into a client.go file.
-
 // This is a small CLI program for simplifying interaction with the IGDB: https://www.igdb.com.
 // Refer to these docs to get started: https://api-docs.igdb.com/#getting-started.
 // And these docs for examples of the endpoints and queries supported: https://api-docs.igdb.com/?shell#examples.
@@ -19,106 +24,477 @@ const (
 	TWITCH_AUTH_URL                = "https://id.twitch.tv/oauth2/token"
 	TWITCH_CLIENT_ID_ENV_VAR       = "CLIENT_ID"
 	TWICTH_CLIENT_SECRET_ENV_VAR   = "CLIENT_SECRET"
+	TWITCH_CREDENTIALS_ENV_VAR     = "CLIENT_CREDENTIALS"
+	ACCESS_TOKEN_ENV_VAR           = "ACCESS_TOKEN"
 	DEFAULT_TWITCH_AUTH_GRANT_TYPE = "client_credentials"
 
-	// Constants for interacting with the IGDB developer API.
-	IGDB_BASE_URL          = "https://api.igdb.com/v4"
-	IGDB_CLIENT_ID_HEADER  = "Client-ID"
-	IGDB_AUTH_TOKEN_HEADER = "Authorization"
-
 	// Defined exit codes for context when the program errors.
-	BAD_USAGE_EXIT_CODE      = 1
-	INTERNAL_ERROR_EXIT_CODE = 2
+	SUCCESS_EXIT_CODE          = 0
+	BAD_USAGE_EXIT_CODE        = 1
+	INTERNAL_ERROR_EXIT_CODE   = 2
+	AUTH_ERROR_EXIT_CODE       = 3
+	RATE_LIMIT_EXIT_CODE       = 4
+	INCOMPLETE_EXIT_CODE       = 5
+	ASSERTION_FAILED_EXIT_CODE = 6
+
+	// Exiting once stdout is closed early mirrors being killed by SIGPIPE, as shells report it.
+	BROKEN_PIPE_EXIT_CODE = 128 + 13
 )
 
-// DatabaseClient is a client for interacting with the IGDB.
-type DatabaseClient struct {
-	clientID  string
-	authToken string
-}
+// Start point of program execution.
+func main() {
+	surfaceBrokenPipes()
 
-// NewDatabaseClient instantiates a new instance of the database client.
-func NewDatabaseClient(clientID string, authToken string) *DatabaseClient {
-	return &DatabaseClient{
-		clientID:  clientID,
-		authToken: authToken,
+	// Parse the command line options.
+	opts, err := parseOptions(os.Args[1:])
+	if err == flag.ErrHelp {
+		printUsage(SUCCESS_EXIT_CODE)
 	}
-}
-
-// newRequest instantiates a new request with the necessary headers.
-func (d *DatabaseClient) newRequest(endpoint string, query string) (*http.Request, error) {
-	reqBody := bytes.NewReader([]byte(query))
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/%s", IGDB_BASE_URL, endpoint), reqBody)
 	if err != nil {
-		return nil, err
+		printUsage(BAD_USAGE_EXIT_CODE)
+	}
+	cfg, err := loadConfig(opts.configPath)
+	if err == nil {
+		applyConfig(opts, cfg)
+	}
+	// The CI defaults override the config file, and apply to the errors loading it too.
+	applyCIDefaults(opts)
+	if validateErrorFormat(opts.errorFormat) == nil {
+		errorFormat = opts.errorFormat
+	}
+	if err != nil {
+		handleErr("failed to load the config file", err, BAD_USAGE_EXIT_CODE)
+	}
+	err = validateOptions(opts)
+	if err != nil {
+		handleErr("invalid flags", err, BAD_USAGE_EXIT_CODE)
 	}
 
-	req.Header.Add(IGDB_CLIENT_ID_HEADER, d.clientID)
-	req.Header.Add(IGDB_AUTH_TOKEN_HEADER, fmt.Sprintf("Bearer %s", d.authToken))
-	return req, nil
-}
+	// Print the shell completion script, if requested.
+	if opts.completion != "" {
+		script, err := completionScript(opts.completion)
+		if err != nil {
+			handleErr("failed to generate completion script", err, BAD_USAGE_EXIT_CODE)
+		}
+		writeStdout(script)
+		return
+	}
+
+	// Print the known endpoints, if requested.
+	if opts.listEndpoints {
+		writeStdout(listEndpoints() + "\n")
+		return
+	}
+
+	// Print the static schema of the endpoints, if requested.
+	if opts.dumpSchema {
+		output, err := encodeOutputJSON(opts, dumpSchema())
+		if err != nil {
+			handleErr("failed to encode the schema", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+		writeStdout(output + "\n")
+		return
+	}
+
+	// Manage the response cache, if requested.
+	if opts.cacheClear || opts.cacheClearEndpoint != "" {
+		endpoint := ""
+		if opts.cacheClearEndpoint != "" {
+			endpoint = resolveEndpoint(opts.cacheClearEndpoint, opts.aliases)
+		}
+		cleared, err := clearResponseCache(endpoint)
+		if err != nil {
+			handleErr("failed to clear the response cache", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+		printfStdout("Cleared %d cached responses\n", cleared)
+		return
+	}
+	if opts.cacheStats {
+		stats, err := statResponseCache()
+		if err != nil {
+			handleErr("failed to read the response cache", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+		writeStdout(stats.String() + "\n")
+		return
+	}
+
+	// Print the fields observed for the endpoint, if requested.
+	if opts.suggestFields != "" {
+		fields, err := suggestFields(resolveEndpoint(opts.suggestFields, opts.aliases))
+		if err != nil {
+			handleErr("failed to suggest fields", err, BAD_USAGE_EXIT_CODE)
+		}
+		writeStdout(strings.Join(fields, "\n") + "\n")
+		return
+	}
 
-// parseResponse parses the response body into a JSON string.
-func (d *DatabaseClient) parseResponse(resp *http.Response) (string, error) {
-	respBody, err := io.ReadAll(resp.Body)
+	// Share one HTTP client so the transport settings apply to every request.
+	httpClient, err := newHTTPClient(opts)
 	if err != nil {
-		return "", err
+		handleErr("failed to configure the HTTP client", err, BAD_USAGE_EXIT_CODE)
 	}
 
-	return string(respBody), nil
-}
+	// Check for a newer release only when asked to, avoiding surprise network calls.
+	if opts.checkUpdate {
+		checkForUpdate(httpClient)
+		if len(opts.args) == 0 {
+			return
+		}
+	}
+
+	// Printing the auth token only authenticates.
+	if opts.printToken {
+		printAuthToken(opts, httpClient)
+		return
+	}
+
+	// Diffing two snapshots doesn't require a query.
+	if opts.diff != "" && opts.diffWith != "" {
+		runSnapshotDiff(opts, opts.diff, opts.diffWith)
+		return
+	}
+
+	// Batches read their endpoints and queries from a file.
+	if opts.batch != "" {
+		runBatch(opts, httpClient)
+		return
+	}
+
+	// The smoke test submits its own queries.
+	if opts.smoke {
+		runSmoke(opts, httpClient)
+		return
+	}
+
+	// The external IDs of a game are resolved from its own endpoint.
+	if opts.crossRef != "" {
+		runCrossRef(opts, httpClient)
+		return
+	}
 
-// Query queries the client database and returns the parsed JSON response.
-func (d *DatabaseClient) Query(endpoint string, query string) (string, error) {
-	req, err := d.newRequest(endpoint, query)
+	// The companies of a game are resolved from several endpoints.
+	if opts.gameCompanies != "" {
+		runGameCompanies(opts, httpClient)
+		return
+	}
+
+	// Validate the user input an endpoint and query.
+	if (len(opts.args) == 0 && !opts.queryStdinJSON && opts.globalSearch == "") || len(opts.args) > 2 {
+		printUsage(BAD_USAGE_EXIT_CODE)
+	}
+	endpoint, query, err := readQuery(opts)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %s", err.Error())
+		handleErr("failed to read the query", err, BAD_USAGE_EXIT_CODE)
+	}
+	endpoint = resolveEndpoint(endpoint, opts.aliases)
+	if opts.lint {
+		reportLint(opts, endpoint, query)
+	}
+	err = validateEndpoint(endpoint)
+	if err != nil {
+		handleErr("failed to validate the endpoint", err, BAD_USAGE_EXIT_CODE)
+	}
+	query, err = prepareQuery(opts, endpoint, query)
+	if err != nil {
+		handleErr("failed to prepare the query", err, BAD_USAGE_EXIT_CODE)
+	}
+
+	// Submit the query and display the results.
+	ctx, cancel := newRunContext(opts)
+	defer cancel()
+	databaseClient := newDatabaseClient(ctx, opts, httpClient)
+
+	// Asserting the count checks it in place of printing the records.
+	if opts.assertCount != "" {
+		runAssertCount(ctx, opts, databaseClient, endpoint, query)
+		return
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	// Streaming prints the records as they arrive.
+	if opts.stream {
+		runStream(ctx, opts, databaseClient, endpoint, query)
+		return
+	}
+
+	// Watching re-runs the query until interrupted.
+	if opts.watch > 0 {
+		runWatch(opts, databaseClient, endpoint, query)
+		return
+	}
+
+	// Serve a stale cached result at once, refreshing it for next time.
+	if opts.swr {
+		stale := loadStaleResponse(opts, endpoint, query)
+		if stale != nil {
+			runStaleWhileRevalidate(ctx, opts, databaseClient, endpoint, query, stale)
+			return
+		}
+	}
+
+	// Confirm large pulls before spending the quota on them.
+	confirmPull(ctx, opts, databaseClient, endpoint, query)
+
+	meta := newResultMeta(endpoint, query)
+	queryResult, err := submitQuery(ctx, opts, databaseClient, endpoint, query)
+	meta.rateLimit = databaseClient.LastRateLimitStatus()
+	if opts.metricsOut != "" {
+		writeMetricsFile(opts.metricsOut, databaseClient)
+	}
+	var incompleteErr *IncompleteResultError
+	if errors.As(err, &incompleteErr) {
+		// Salvage the pages fetched before the interruption.
+		printResult(opts, meta, queryResult)
+		reportHighWaterMark(opts, queryResult)
+		explainRateLimit(opts, databaseClient)
+		handleQueryErr(opts, "failed to fetch every page of results", err)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to do request: %s", err.Error())
+		explainRateLimit(opts, databaseClient)
+		handleQueryErr(opts, "failed to query the internet games database", err)
 	}
 
-	parsedResp, err := d.parseResponse(resp)
+	reportHighWaterMark(opts, queryResult)
+	err = handleResult(opts, meta, queryResult)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse response: %s", err.Error())
+		failAssertion(err)
+	}
+}
+
+// handleResult checks the fetched result, narrows it to the changed records and outputs it as
+// requested, returning the first assertion failing to hold in place of outputting it.
+func handleResult(opts *options, meta *resultMeta, queryResult string) error {
+	endpoint, query := meta.endpoint, meta.query
+	var err error
+
+	// Check the results are fresh and valid before outputting them.
+	if opts.maxAge > 0 {
+		if err := checkMaxAge(opts, endpoint, queryResult); err != nil {
+			return err
+		}
+	}
+	if opts.schema != "" {
+		if err := checkSchema(opts, endpoint, queryResult); err != nil {
+			return err
+		}
 	}
 
-	return parsedResp, nil
+	// Learn the fields of the endpoint from exploratory queries.
+	if isWildcardQuery(query) && endpoint != MULTIQUERY_ENDPOINT && !strings.HasSuffix(endpoint, COUNT_ENDPOINT_SUFFIX) {
+		err = recordObservedFields(endpoint, queryResult)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to cache the observed fields with error: %s\n", err.Error())
+		}
+	}
+
+	// Narrow the results to those changed since the snapshot, e.g. for change data capture.
+	if opts.onlyChanged != "" {
+		queryResult, err = onlyChangedResult(opts, opts.onlyChanged, queryResult)
+		if err != nil {
+			handleErr("failed to find the changed records", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+	}
+
+	// Write the results in parts rather than printing them, if requested.
+	if opts.splitSize > 0 || opts.splitBytes != "" {
+		runSplit(opts, meta, queryResult)
+		return nil
+	}
+
+	// Write the post-processed results to SQLite rather than printing them, if requested.
+	if opts.sqlite != "" {
+		queryResult, err = processResult(opts, meta, queryResult)
+		if err != nil {
+			handleErr("failed to process the query result", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+		rows, err := writeSQLite(opts.sqlite, endpoint, queryResult)
+		if err != nil {
+			handleErr("failed to write the results to SQLite", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+		printfStdout("Wrote %d rows to the %s table of %s\n", rows, endpoint, opts.sqlite)
+		return nil
+	}
+
+	// Compare the results against a previous snapshot, if requested.
+	if opts.diff != "" {
+		runLiveDiff(opts, opts.diff, queryResult)
+		return nil
+	}
+
+	printResult(opts, meta, queryResult)
+	return nil
 }
 
-// Ideally, the following would be separated into the main.go file.
+// newRunContext instantiates the context of a run, which stops cleanly on Ctrl-C or once the timeout elapses.
+func newRunContext(opts *options) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if opts.timeout <= 0 {
+		return ctx, stop
+	}
 
-// Start point of program execution.
-func main() {
-	// Validate the user input an endpoint and query.
-	if len(os.Args) != 3 {
-		printUsage(BAD_USAGE_EXIT_CODE)
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
 	}
+}
 
+// newDatabaseClient authenticates within the context and instantiates the database client
+// configured by the options.
+func newDatabaseClient(ctx context.Context, opts *options, httpClient *http.Client) *DatabaseClient {
 	// Initiliaze client data and get auth token.
-	clientID, clientSecret, err := getClientIDAndSecret()
+	retry := newAuthRetries(opts)
+	clientID, authToken := authenticate(ctx, opts, httpClient, retry)
+
+	databaseClient := NewDatabaseClient(clientID, authToken)
+	databaseClient.SetHTTPClient(httpClient)
+	if opts.multiCredentials {
+		addCredentials(ctx, databaseClient, httpClient, retry, clientID)
+	}
+	databaseClient.SetAccept(opts.accept)
+	databaseClient.SetLogger(newLogger(opts.verbose))
+	if opts.trace {
+		databaseClient.SetTrace(os.Stderr)
+	}
+	databaseClient.SetMaxResponseSize(opts.maxResponseSize)
+	databaseClient.SetRateLimit(opts.rateLimit)
+	databaseClient.SetRetries(opts.retries, opts.retryDelay)
+	databaseClient.shareRetryBudget(retry.budget)
+	return databaseClient
+}
+
+// submitQuery submits the query to the endpoint, serving it from the response cache while fresh
+// when -cache is set.
+func submitQuery(ctx context.Context, opts *options, databaseClient *DatabaseClient, endpoint string, query string) (string, error) {
+	if opts.cache <= 0 {
+		return fetchValidResult(ctx, opts, databaseClient, endpoint, query)
+	}
+
+	key := cacheKey(opts, query)
+	cached, err := loadCachedResponse(endpoint, key)
 	if err != nil {
-		handleErr("failed to retrieve client ID and secret", err, INTERNAL_ERROR_EXIT_CODE)
+		fmt.Fprintf(os.Stderr, "failed to read the response cache with error: %s\n", err.Error())
+	}
+	if cached != nil && cached.isFresh(opts.cache) {
+		databaseClient.logger.Printf("Served from the response cache, fetched at %s", cached.FetchedAt.Format(time.RFC3339))
+		return cached.Result, nil
 	}
-	authToken, err := getAuthToken(clientID, clientSecret)
+
+	result, err := fetchValidResult(ctx, opts, databaseClient, endpoint, query)
+	if err == nil {
+		cacheResult(endpoint, key, result)
+	}
+	return result, err
+}
+
+// fetchValidResult fetches the result, failing with -validate-json unless it's valid JSON, e.g.
+// when a proxy responded with an HTML error page.
+func fetchValidResult(ctx context.Context, opts *options, databaseClient *DatabaseClient, endpoint string, query string) (string, error) {
+	result, err := fetchResult(ctx, opts, databaseClient, endpoint, query)
+
+	// Records published moments ago may not be returned yet, so empty results are retried.
+	for attempt := 0; err == nil && attempt < opts.retryOnEmpty && isEmptyResult(result); attempt++ {
+		databaseClient.logger.Printf("Retrying the empty result after %s", opts.retryOnEmptyDelay)
+		err = sleepContext(ctx, opts.retryOnEmptyDelay)
+		if err == nil {
+			result, err = fetchResult(ctx, opts, databaseClient, endpoint, query)
+		}
+	}
+	if err != nil || !opts.validateJSON {
+		return result, err
+	}
+	err = validateJSON(result)
 	if err != nil {
-		handleErr("failed to get auth token", err, INTERNAL_ERROR_EXIT_CODE)
+		return "", err
 	}
+	return result, nil
+}
 
-	// Get input from the user for the query.
-	endpoint := os.Args[1]
-	query := os.Args[2]
+// fetchResult fetches the result of the query from the endpoint, handling any endpoint specific behaviour.
+func fetchResult(ctx context.Context, opts *options, databaseClient *DatabaseClient, endpoint string, query string) (string, error) {
+	if endpoint == MULTIQUERY_ENDPOINT {
+		return databaseClient.MultiqueryContext(ctx, query)
+	}
+	var result string
+	var err error
+	if opts.idsFile != "" {
+		result, err = fetchByIDs(ctx, opts, databaseClient, endpoint, query)
+	} else if (opts.all || opts.since != "") && opts.parallelPages {
+		result, err = fetchAllPagesInParallel(ctx, databaseClient, endpoint, query, pageConcurrency(opts, len(databaseClient.credentials)))
+	} else if opts.all || opts.since != "" {
+		result, err = fetchAllPages(ctx, databaseClient, endpoint, query)
+	} else {
+		result, err = databaseClient.QueryContext(ctx, endpoint, query)
+	}
+	if err != nil || endpoint != SEARCH_ENDPOINT {
+		return result, err
+	}
+	return labelSearchResults(result)
+}
 
-	// Submit the query and display the results.
-	databaseClient := NewDatabaseClient(clientID, authToken)
-	queryResult, err := databaseClient.Query(endpoint, query)
+// reportHighWaterMark reports the latest update seen by -since so the next incremental pull can start from it.
+func reportHighWaterMark(opts *options, queryResult string) {
+	if opts.since == "" {
+		return
+	}
+
+	mark, err := highWaterMark(queryResult)
 	if err != nil {
-		handleErr("failed to query the internet games database", err, INTERNAL_ERROR_EXIT_CODE)
+		handleErr("failed to determine the high-water mark", err, INTERNAL_ERROR_EXIT_CODE)
+	}
+	fmt.Fprintf(os.Stderr, "High-water mark: %d\n", mark)
+}
+
+// printResult applies any post-processing of the query result and displays it.
+func printResult(opts *options, meta *resultMeta, queryResult string) {
+	queryResult, err := processResult(opts, meta, queryResult)
+	if err != nil {
+		handleErr("failed to process the query result", err, INTERNAL_ERROR_EXIT_CODE)
+	}
+
+	printOutput(opts, queryResult)
+}
+
+// printOutput displays the output beneath the banner, after formatting it with -formatter and
+// piping it through -pipe if set, and pages it when paging.
+func printOutput(opts *options, output string) {
+	if opts.formatter != "" {
+		formatted, err := formatOutput(opts.formatter, output)
+		var pipeErr *pipeError
+		if errors.As(err, &pipeErr) {
+			handleErr("failed to format the output", err, pipeErr.exitCode)
+		}
+		if err != nil {
+			handleErr("failed to format the output", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+		output = formatted
+	}
+
+	if opts.pipe != "" {
+		piped, err := pipeOutput(opts.pipe, output)
+		var pipeErr *pipeError
+		if errors.As(err, &pipeErr) {
+			handleErr("failed to pipe the output", err, pipeErr.exitCode)
+		}
+		if err != nil {
+			handleErr("failed to pipe the output", err, INTERNAL_ERROR_EXIT_CODE)
+		}
+		output = piped
 	}
 
-	fmt.Printf("Query result: \n%s\n", queryResult)
+	if opts.banner == "" {
+		printPaged(opts, terminateOutput(opts, output))
+		return
+	}
+	printPaged(opts, fmt.Sprintf("%s \n%s", colorize(opts, ANSI_BOLD, opts.banner), terminateOutput(opts, output)))
+}
+
+// terminateOutput ends the output with exactly one newline, or none with -no-trailing-newline.
+func terminateOutput(opts *options, output string) string {
+	output = strings.TrimRight(output, "\r\n")
+	if opts.noTrailingNewline {
+		return output
+	}
+	return output + "\n"
 }
 
 // twitchAuthBody represents the JSON request body for Twitch developer authentication.
@@ -132,14 +508,71 @@ type twitchAuthBody struct {
 type twitchAuthResponse struct {
 	AccessToken string `json:"access_token"`
 	ExpiresIn   int32  `json:"expires_in"`
-	TokenType   string `json:"expires_in"`
+	TokenType   string `json:"token_type"`
 }
 
-// getClientIDAndSecret retrieves the client data from the local environment.
-func getClientIDAndSecret() (string, string, error) {
+// twitchAuthError represents the JSON error body of failed Twitch developer authentication, e.g.
+// for an invalid client secret.
+type twitchAuthError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// authenticate retrieves the client ID along with an auth token, either provided directly or
+// retrieved from the Twitch developer API.
+func authenticate(ctx context.Context, opts *options, httpClient *http.Client, retry *authRetries) (string, string) {
+	accessToken := opts.accessToken
+	if accessToken == "" {
+		accessToken = os.Getenv(ACCESS_TOKEN_ENV_VAR)
+	}
+
+	// A provided token skips authentication, but the IGDB still requires the client ID.
+	if accessToken != "" {
+		clientID, err := getClientID()
+		if err != nil {
+			handleErr("failed to retrieve client ID", err, AUTH_ERROR_EXIT_CODE)
+		}
+		return clientID, accessToken
+	}
+
+	// Interactive authorization requires only the client ID, not the secret.
+	if opts.interactiveAuth {
+		clientID, err := getClientID()
+		if err != nil {
+			handleErr("failed to retrieve client ID", err, AUTH_ERROR_EXIT_CODE)
+		}
+		authToken, err := getInteractiveAuthToken(ctx, httpClient, clientID)
+		if err != nil {
+			handleErr("failed to authorize interactively", err, AUTH_ERROR_EXIT_CODE)
+		}
+		return clientID, authToken
+	}
+
+	clientID, clientSecret, err := getClientIDAndSecret()
+	if err != nil {
+		handleErr("failed to retrieve client ID and secret", err, AUTH_ERROR_EXIT_CODE)
+	}
+	authToken, err := getAuthToken(ctx, httpClient, retry, clientID, clientSecret)
+	if err != nil {
+		handleErr("failed to get auth token", err, AUTH_ERROR_EXIT_CODE)
+	}
+	return clientID, authToken
+}
+
+// getClientID retrieves the client ID from the local environment.
+func getClientID() (string, error) {
 	clientID := os.Getenv(TWITCH_CLIENT_ID_ENV_VAR)
 	if clientID == "" {
-		return "", "", fmt.Errorf("%s must be initialized", TWITCH_CLIENT_ID_ENV_VAR)
+		return "", fmt.Errorf("%s must be initialized", TWITCH_CLIENT_ID_ENV_VAR)
+	}
+	return clientID, nil
+}
+
+// getClientIDAndSecret retrieves the client data from the local environment.
+func getClientIDAndSecret() (string, string, error) {
+	clientID, err := getClientID()
+	if err != nil {
+		return "", "", err
 	}
 
 	clientSecret := os.Getenv(TWICTH_CLIENT_SECRET_ENV_VAR)
@@ -150,48 +583,274 @@ func getClientIDAndSecret() (string, string, error) {
 	return clientID, clientSecret, nil
 }
 
+// clientIDAndSecret is a pair of client credentials for the Twitch developer API.
+type clientIDAndSecret struct {
+	clientID     string
+	clientSecret string
+}
+
+// getAdditionalClientIDsAndSecrets retrieves additional client data from the local environment, formatted
+// as comma separated id:secret pairs, rejecting any client ID that is used more than once.
+func getAdditionalClientIDsAndSecrets(primaryClientID string) ([]clientIDAndSecret, error) {
+	credentials := os.Getenv(TWITCH_CREDENTIALS_ENV_VAR)
+	if credentials == "" {
+		return nil, fmt.Errorf("%s must be initialized", TWITCH_CREDENTIALS_ENV_VAR)
+	}
+
+	seen := map[string]bool{primaryClientID: true}
+	pairs := []clientIDAndSecret{}
+	for _, pair := range strings.Split(credentials, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%s must contain comma separated id:secret pairs", TWITCH_CREDENTIALS_ENV_VAR)
+		}
+		if seen[parts[0]] {
+			return nil, fmt.Errorf("client ID %s is used more than once", parts[0])
+		}
+		seen[parts[0]] = true
+		pairs = append(pairs, clientIDAndSecret{clientID: parts[0], clientSecret: parts[1]})
+	}
+
+	return pairs, nil
+}
+
+// addCredentials authenticates the additional client data and adds it to the database client.
+func addCredentials(ctx context.Context, databaseClient *DatabaseClient, httpClient *http.Client, retry *authRetries, primaryClientID string) {
+	pairs, err := getAdditionalClientIDsAndSecrets(primaryClientID)
+	if err != nil {
+		handleErr("failed to retrieve additional client IDs and secrets", err, AUTH_ERROR_EXIT_CODE)
+	}
+
+	for _, pair := range pairs {
+		authToken, err := getAuthToken(ctx, httpClient, retry, pair.clientID, pair.clientSecret)
+		if err != nil {
+			handleErr(fmt.Sprintf("failed to get auth token for client ID %s", pair.clientID), err, AUTH_ERROR_EXIT_CODE)
+		}
+		databaseClient.AddCredentials(pair.clientID, authToken)
+	}
+}
+
 // getAuthToken retrieves a valid auth token from the Twitch developer API.
-func getAuthToken(clientID string, clientSecret string) (string, error) {
+func getAuthToken(ctx context.Context, httpClient *http.Client, retry *authRetries, clientID string, clientSecret string) (string, error) {
+	auth, err := requestAuthToken(ctx, httpClient, retry, clientID, clientSecret)
+	if err != nil {
+		return "", err
+	}
+	return auth.AccessToken, nil
+}
+
+// authRetries is the retrying of failed auth requests, configured like that of the queries by
+// -retries and -retry-delay, and drawing on the same -retry-budget.
+type authRetries struct {
+	retries int
+	delay   time.Duration
+	budget  *retryBudget
+	jitter  *retryJitter
+	logger  *log.Logger
+}
+
+// newAuthRetries instantiates the retrying of auth requests configured by the options.
+func newAuthRetries(opts *options) *authRetries {
+	return &authRetries{
+		retries: opts.retries,
+		delay:   opts.retryDelay,
+		budget:  &retryBudget{limit: opts.retryBudget},
+		jitter:  newRetryJitter(),
+		logger:  newLogger(opts.verbose),
+	}
+}
+
+// requestAuthToken requests an auth token from the Twitch developer API, along with its expiry.
+// Throttled and server error responses, and failed requests, are retried with jittered backoff
+// until the context is done, while rejected credentials fail straight away.
+func requestAuthToken(ctx context.Context, httpClient *http.Client, retry *authRetries, clientID string, clientSecret string) (*twitchAuthResponse, error) {
 	// Setup the request body.
 	reqBody := &twitchAuthBody{
-		ClientID:     os.Getenv(TWITCH_CLIENT_ID_ENV_VAR),
-		ClientSecret: os.Getenv(TWICTH_CLIENT_SECRET_ENV_VAR),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
 		GrantType:    DEFAULT_TWITCH_AUTH_GRANT_TYPE,
 	}
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	bodyReader := bytes.NewReader(bodyBytes)
 
-	// Perform the request.
-	resp, err := http.Post(TWITCH_AUTH_URL, "application/json", bodyReader)
+	for attempt := 0; ; attempt++ {
+		// Perform the request.
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, TWITCH_AUTH_URL, bytes.NewReader(bodyBytes))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(IGDB_CONTENT_TYPE_HEADER, "application/json")
+		resp, err := httpClient.Do(req)
+		retryable := (err != nil && ctx.Err() == nil) || (err == nil && isRetryableStatus(resp.StatusCode))
+		if !retryable || attempt >= retry.retries || !retry.budget.take(retry.logger) {
+			if err != nil {
+				return nil, err
+			}
+			defer closeBody(resp)
+			return parseAuthResponse(resp)
+		}
+
+		// Failed requests have no response to honour the Retry-After header of.
+		retryResp := resp
+		if retryResp == nil {
+			retryResp = &http.Response{}
+		} else {
+			closeBody(resp)
+		}
+		delay := retry.jitter.backoff(retryResp, retry.delay, attempt)
+		retry.logger.Printf("Retrying the auth request after %s", delay)
+		err = sleepContext(ctx, delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseAuthResponse parses the auth token from the response, or the reason it was refused from
+// the error body when unsuccessful.
+func parseAuthResponse(resp *http.Response) (*twitchAuthResponse, error) {
+	respBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		authErr := &twitchAuthError{}
+		if json.Unmarshal(respBytes, authErr) == nil && authErr.Message != "" {
+			return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, authErr.Message)
+		}
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, string(respBytes))
 	}
 
 	// Parse the response body.
 	respBody := &twitchAuthResponse{}
-	respBytes, err := io.ReadAll(resp.Body)
+	err = json.Unmarshal(respBytes, respBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	err = json.Unmarshal(respBytes, respBody)
+	if respBody.AccessToken == "" {
+		return nil, errors.New("the response contained no access token")
+	}
+
+	return respBody, nil
+}
+
+// printAuthToken authenticates with the client secret and prints the auth token on the first line
+// of stdout, e.g. for use with curl, and the time it expires on the second.
+func printAuthToken(opts *options, httpClient *http.Client) {
+	clientID, clientSecret, err := getClientIDAndSecret()
 	if err != nil {
-		return "", err
+		handleErr("failed to retrieve client ID and secret", err, AUTH_ERROR_EXIT_CODE)
+	}
+	ctx, cancel := newRunContext(opts)
+	defer cancel()
+	auth, err := requestAuthToken(ctx, httpClient, newAuthRetries(opts), clientID, clientSecret)
+	if err != nil {
+		handleErr("failed to get auth token", err, AUTH_ERROR_EXIT_CODE)
 	}
+	fmt.Fprintf(os.Stderr, "WARNING: the auth token grants access to the IGDB as your application, keep it secret\n")
+	expiresAt := time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second).UTC()
+	writeStdout(auth.AccessToken + "\n")
+	printfStdout("expires %s\n", expiresAt.Format(time.RFC3339))
+}
 
-	return respBody.AccessToken, nil
+// newLogger instantiates a logger writing to stderr when verbose, discarding output otherwise.
+func newLogger(verbose bool) *log.Logger {
+	if !verbose {
+		return log.New(io.Discard, "", 0)
+	}
+	return log.New(os.Stderr, "[verbose] ", 0)
 }
 
 // printUsage prints the program's usage to the console and exits.
 func printUsage(exitCode int) {
-	fmt.Printf("Usage: gamers-console \"<endpoint>\" \"<query>\"\n")
+	printfStdout("Usage: gamers-console [flags] \"<endpoint>\" \"<query>\"\n")
+	printfStdout("Flags:\n")
+	flags.PrintDefaults()
+	printfStdout("Default flags:\n")
+	printfStdout("  Flags in %s, e.g. \"-format csv -rate-limit 2\", apply to every run. Flags on the\n", DEFAULT_OPTIONS_ENV_VAR)
+	printfStdout("  command line take precedence over them, and both take precedence over the config file.\n")
+	printfStdout("Multiple credentials:\n")
+	printfStdout("  -multi-credentials spreads requests across the client ID and secret along with the comma\n")
+	printfStdout("  separated id:secret pairs in %s, rate limiting each independently. Using several\n", TWITCH_CREDENTIALS_ENV_VAR)
+	printfStdout("  applications to exceed the rate limits of one may breach the Twitch Developer Services\n")
+	printfStdout("  Agreement, so ensure your use is permitted before enabling it.\n")
+	printfStdout("Interactive authorization:\n")
+	printfStdout("  -interactive-auth authorizes in the browser with the Twitch device code flow instead of the\n")
+	printfStdout("  client secret, which lets applications registered as public clients query without one. The\n")
+	printfStdout("  IGDB may reject user tokens for some applications, in which case use the client secret.\n")
+	printfStdout("Retrying empty results:\n")
+	printfStdout("  Records published moments ago may not be returned straight away, since the IGDB is only\n")
+	printfStdout("  eventually consistent. -retry-on-empty re-runs queries returning no records in case they\n")
+	printfStdout("  appear, which can't tell a record that's yet to appear from one that doesn't exist.\n")
+	printfStdout("Response cache:\n")
+	printfStdout("  -cache serves responses cached within the duration without a request. With -swr, expired\n")
+	printfStdout("  responses are printed straight away and refreshed before exiting, so results arrive quickly\n")
+	printfStdout("  but may be stale, and the run still waits on the refresh. -cache-stats reports on the cached\n")
+	printfStdout("  responses, which -cache-clear and -cache-clear-endpoint remove.\n")
+	printfStdout("Query macros:\n")
+	printfStdout("  The macros of the config file, e.g. \"macros\": {\"recent\": \"where first_release_date > <now-30d>;\"},\n")
+	printfStdout("  are expanded wherever the query references them, e.g. {recent}, and may reference each other\n")
+	printfStdout("  up to %d deep. <now> and relative times like <now-30d> or <now+2w> become unix epochs.\n", MAX_MACRO_DEPTH)
+	printfStdout("Safe mode:\n")
+	printfStdout("  -safe-mode guards the quota of shared credentials, e.g. in demo environments, by capping\n")
+	printfStdout("  limits and disabling -all, -since, -ids-file, -watch, -game-companies, -cross-ref and\n")
+	printfStdout("  multiqueries. Set %s=true in the environment of every user, e.g. in /etc/environment,\n", SAFE_MODE_ENV_VAR)
+	printfStdout("  to enable it organization wide.\n")
+	printfStdout("Exit codes:\n")
+	printfStdout("  %d\tsuccess\n", SUCCESS_EXIT_CODE)
+	printfStdout("  %d\tbad usage, e.g. invalid flags or a missing query\n", BAD_USAGE_EXIT_CODE)
+	printfStdout("  %d\tinternal error, e.g. a failed request or unexpected response\n", INTERNAL_ERROR_EXIT_CODE)
+	printfStdout("  %d\tauthentication error, e.g. missing or rejected credentials\n", AUTH_ERROR_EXIT_CODE)
+	printfStdout("  %d\trate limited by the internet games database\n", RATE_LIMIT_EXIT_CODE)
+	printfStdout("  %d\tincomplete results, e.g. paging was interrupted after some pages were printed\n", INCOMPLETE_EXIT_CODE)
+	printfStdout("  %d\tassertion failed, i.e. the count didn't satisfy -assert-count, the records exceeded -max-age or violated -schema\n", ASSERTION_FAILED_EXIT_CODE)
+	printfStdout("  %d\tstdout was closed before the output was written, e.g. when piped into head\n", BROKEN_PIPE_EXIT_CODE)
 	os.Exit(exitCode)
 }
 
+// exitCodeFor maps an error returned by the database client to its exit code.
+func exitCodeFor(err error) int {
+	if errors.Is(err, ErrMissingClientID) {
+		return AUTH_ERROR_EXIT_CODE
+	}
+	var incompleteErr *IncompleteResultError
+	if errors.As(err, &incompleteErr) {
+		return INCOMPLETE_EXIT_CODE
+	}
+
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		return INTERNAL_ERROR_EXIT_CODE
+	}
+
+	switch respErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return AUTH_ERROR_EXIT_CODE
+	case http.StatusTooManyRequests:
+		return RATE_LIMIT_EXIT_CODE
+	default:
+		return INTERNAL_ERROR_EXIT_CODE
+	}
+}
+
+// describeQueryErr summarizes structured response errors when pretty errors are enabled.
+func describeQueryErr(opts *options, err error) error {
+	var respErr *ResponseError
+	if opts.prettyErrors && errors.As(err, &respErr) {
+		return errors.New(respErr.Pretty())
+	}
+	return err
+}
+
+// handleQueryErr is a helper function for handling errors returned by the database client and exiting.
+func handleQueryErr(opts *options, message string, err error) {
+	handleErr(message, describeQueryErr(opts, err), exitCodeFor(err))
+}
+
 // handleErr is a helper function for handling errors and exiting.
 func handleErr(message string, err error, exitCode int) {
-	fmt.Printf("%s with error: %s", message, err.Error())
+	printErr(message, err, exitCode)
 	os.Exit(exitCode)
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestAuthClient instantiates an HTTP client sending its requests to a test server serving the
+// handler, counting the requests it receives.
+func newTestAuthClient(t *testing.T, handler http.HandlerFunc) (*http.Client, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Transport: &rewriteTransport{target: target, next: http.DefaultTransport}}, &requests
+}
+
+func TestRequestAuthTokenRetries(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		requests int32
+	}{
+		{"configured retries", []string{"-retries", "3", "-retry-delay", "1ms"}, 4},
+		{"no retries", []string{"-retries", "0"}, 1},
+		{"retry budget", []string{"-retries", "5", "-retry-delay", "1ms", "-retry-budget", "2"}, 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			httpClient, requests := newTestAuthClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+			retry := newAuthRetries(parseTestOptions(t, c.args...))
+
+			_, err := requestAuthToken(context.Background(), httpClient, retry, "client-id", "client-secret")
+			if err == nil {
+				t.Fatal("expected the unavailable auth server to fail the request")
+			}
+			if *requests != c.requests {
+				t.Errorf("expected %d requests, got %d", c.requests, *requests)
+			}
+		})
+	}
+}
+
+func TestRequestAuthTokenStopsWithTheContext(t *testing.T) {
+	httpClient, _ := newTestAuthClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	retry := newAuthRetries(parseTestOptions(t, "-retries", "5", "-retry-delay", "1m"))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err := requestAuthToken(ctx, httpClient, retry, "client-id", "client-secret")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context's error, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("expected the retries to stop with the context, took %s", elapsed)
+	}
+}
+
+func TestRequestAuthTokenSucceedsAfterRetrying(t *testing.T) {
+	var attempts int32
+	httpClient, _ := newTestAuthClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, `{"access_token":"token","expires_in":3600,"token_type":"bearer"}`)
+	})
+	retry := newAuthRetries(parseTestOptions(t, "-retry-delay", "1ms"))
+
+	auth, err := requestAuthToken(context.Background(), httpClient, retry, "client-id", "client-secret")
+	if err != nil {
+		t.Fatalf("expected the retried request to succeed: %s", err.Error())
+	}
+	if auth.AccessToken != "token" {
+		t.Errorf("expected the access token, got %q", auth.AccessToken)
+	}
+}
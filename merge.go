@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// The field joining the results with -merge-with-file by default.
+	DEFAULT_MERGE_KEY = "id"
+)
+
+// loadMergeRecords reads the JSON array of local records at the path, keyed by their join key.
+func loadMergeRecords(path string, key string) (map[string]map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeJSON(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %s", path, err.Error())
+	}
+	records, ok := decoded.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must hold a JSON array of objects", path)
+	}
+
+	keyed := map[string]map[string]interface{}{}
+	for i, record := range records {
+		object, ok := record.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("element %d of %s isn't an object", i, path)
+		}
+		value, ok := object[key]
+		if !ok || value == nil {
+			return nil, fmt.Errorf("element %d of %s has no %s", i, path, key)
+		}
+		cell, err := formatCell(value)
+		if err != nil {
+			return nil, err
+		}
+		keyed[cell] = object
+	}
+	return keyed, nil
+}
+
+// mergeRecords left joins the records of the result with the local records sharing the value of
+// their join key, adding the local fields the records lack, or replacing theirs too when the local
+// values override. Records without a local match are left as they are.
+func mergeRecords(result interface{}, local map[string]map[string]interface{}, key string, override bool) interface{} {
+	records, ok := result.([]interface{})
+	if !ok {
+		return result
+	}
+	for _, record := range records {
+		object, ok := record.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		cell, err := formatCell(object[key])
+		if err != nil {
+			continue
+		}
+		match, ok := local[cell]
+		if !ok {
+			continue
+		}
+		for field, value := range match {
+			if _, exists := object[field]; !exists || override {
+				object[field] = value
+			}
+		}
+	}
+	return records
+}
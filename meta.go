@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// resultMeta describes the provenance of a query result.
+type resultMeta struct {
+	endpoint  string
+	query     string
+	fetchedAt time.Time
+
+	// The rate limit status reported while fetching the result, if any.
+	rateLimit *RateLimitStatus
+}
+
+// newResultMeta describes a result of the query to the endpoint fetched now.
+func newResultMeta(endpoint string, query string) *resultMeta {
+	return &resultMeta{
+		endpoint:  endpoint,
+		query:     query,
+		fetchedAt: time.Now().UTC(),
+	}
+}
+
+// resultEnvelope wraps a result with its provenance for -with-meta.
+type resultEnvelope struct {
+	Endpoint    string           `json:"endpoint"`
+	Query       string           `json:"query"`
+	Count       int              `json:"count"`
+	FetchedAt   string           `json:"fetched_at"`
+	RequestHash string           `json:"request_hash"`
+	RateLimit   *RateLimitStatus `json:"rate_limit,omitempty"`
+	Results     interface{}      `json:"results"`
+}
+
+// wrapResult wraps the decoded result in an envelope describing its provenance.
+func wrapResult(meta *resultMeta, result interface{}) *resultEnvelope {
+	count := 1
+	if records, ok := result.([]interface{}); ok {
+		count = len(records)
+	}
+
+	return &resultEnvelope{
+		Endpoint:    meta.endpoint,
+		Query:       meta.query,
+		Count:       count,
+		FetchedAt:   meta.fetchedAt.Format(time.RFC3339),
+		RequestHash: requestHash(meta.endpoint, meta.query),
+		RateLimit:   meta.rateLimit,
+		Results:     result,
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// The prefix of the names of the metrics written by -metrics-out.
+	METRICS_PREFIX = "gamers_console"
+)
+
+// latencyBuckets are the upper bounds in seconds of the buckets of the request latency histogram.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// requestMetrics counts the requests made by the database client, by outcome and latency.
+type requestMetrics struct {
+	mu sync.Mutex
+
+	requests int
+	retries  int
+	failures int
+
+	// The number of responses of each status class, e.g. 2xx.
+	statuses map[string]int
+
+	// The number of requests completed within each latency bucket, and their total latency.
+	latencies     []int
+	latencySum    time.Duration
+	latencyCounts int
+}
+
+// newRequestMetrics instantiates metrics counting no requests.
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{
+		statuses:  map[string]int{},
+		latencies: make([]int, len(latencyBuckets)),
+	}
+}
+
+// observe counts a request completed in the elapsed time with the status code, or zero when no
+// response was received.
+func (m *requestMetrics) observe(statusCode int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests++
+	if statusCode == 0 {
+		m.failures++
+	} else {
+		m.statuses[fmt.Sprintf("%dxx", statusCode/100)]++
+	}
+
+	for i, bound := range latencyBuckets {
+		if elapsed.Seconds() <= bound {
+			m.latencies[i]++
+		}
+	}
+	m.latencySum += elapsed
+	m.latencyCounts++
+}
+
+// observeRetry counts a retried request.
+func (m *requestMetrics) observeRetry() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries++
+}
+
+// WriteMetrics writes the metrics of the requests made so far in the Prometheus text format.
+func (d *DatabaseClient) WriteMetrics(w io.Writer) error {
+	m := d.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lines := []string{}
+	counter := func(name string, help string, value int) {
+		lines = append(lines,
+			fmt.Sprintf("# HELP %s_%s %s", METRICS_PREFIX, name, help),
+			fmt.Sprintf("# TYPE %s_%s counter", METRICS_PREFIX, name),
+			fmt.Sprintf("%s_%s %d", METRICS_PREFIX, name, value),
+		)
+	}
+	counter("requests_total", "Requests made to the internet games database.", m.requests)
+	counter("retries_total", "Requests retried after throttled or failed responses.", m.retries)
+	counter("request_failures_total", "Requests failing without a response, e.g. timeouts.", m.failures)
+
+	classes := []string{}
+	for class := range m.statuses {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	lines = append(lines,
+		fmt.Sprintf("# HELP %s_responses_total Responses received by status class.", METRICS_PREFIX),
+		fmt.Sprintf("# TYPE %s_responses_total counter", METRICS_PREFIX),
+	)
+	for _, class := range classes {
+		lines = append(lines, fmt.Sprintf("%s_responses_total{class=%q} %d", METRICS_PREFIX, class, m.statuses[class]))
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("# HELP %s_request_duration_seconds Latency of the requests.", METRICS_PREFIX),
+		fmt.Sprintf("# TYPE %s_request_duration_seconds histogram", METRICS_PREFIX),
+	)
+	for i, bound := range latencyBuckets {
+		lines = append(lines, fmt.Sprintf("%s_request_duration_seconds_bucket{le=\"%g\"} %d", METRICS_PREFIX, bound, m.latencies[i]))
+	}
+	lines = append(lines,
+		fmt.Sprintf("%s_request_duration_seconds_bucket{le=\"+Inf\"} %d", METRICS_PREFIX, m.latencyCounts),
+		fmt.Sprintf("%s_request_duration_seconds_sum %g", METRICS_PREFIX, m.latencySum.Seconds()),
+		fmt.Sprintf("%s_request_duration_seconds_count %d", METRICS_PREFIX, m.latencyCounts),
+	)
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// writeMetricsFile writes the metrics of the client's requests to the path, replacing it at once
+// so that collectors reading it, e.g. the node_exporter textfile collector, never read it partially.
+func writeMetricsFile(path string, databaseClient *DatabaseClient) {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the metrics with error: %s\n", err.Error())
+		return
+	}
+	defer os.Remove(temp.Name())
+
+	err = databaseClient.WriteMetrics(temp)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the metrics with error: %s\n", err.Error())
+	}
+}
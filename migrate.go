@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// deprecatedFields maps the endpoints to their renamed fields, from the deprecated name to the current one.
+var deprecatedFields = map[string]map[string]string{
+	"age_ratings": {
+		"category": "organization",
+		"rating":   "rating_category",
+	},
+	"companies": {
+		"change_date_category": "change_date_format",
+		"start_date_category":  "start_date_format",
+	},
+	"external_games": {
+		"category": "external_game_source",
+	},
+	"games": {
+		"category": "game_type",
+		"status":   "game_status",
+	},
+	"platforms": {
+		"category": "platform_type",
+	},
+	"release_dates": {
+		"category": "date_format",
+		"region":   "release_region",
+	},
+	"websites": {
+		"category": "type",
+	},
+}
+
+// migratedClauses are the clauses whose values reference fields.
+var migratedClauses = map[string]bool{"fields": true, "exclude": true, "where": true, "sort": true}
+
+// migrateFields rewrites the deprecated fields of the endpoint referenced by the query to their
+// current names, warning about each rewrite on stderr.
+func migrateFields(endpoint string, q *apicalypseQuery) {
+	renames, ok := deprecatedFields[strings.TrimSuffix(endpoint, COUNT_ENDPOINT_SUFFIX)]
+	if !ok {
+		return
+	}
+
+	migrated := map[string]bool{}
+	for i, clause := range q.clauses {
+		if !migratedClauses[clause.keyword] {
+			continue
+		}
+		q.clauses[i].value = renameFields(clause.value, renames, migrated)
+	}
+	fields := []string{}
+	for field := range migrated {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		fmt.Fprintf(os.Stderr, "warning: migrated the deprecated field %s.%s to %s\n", endpoint, field, renames[field])
+	}
+}
+
+// renameFields renames the top level fields within the clause value, leaving nested fields and
+// strings untouched, and notes which fields were renamed.
+func renameFields(value string, renames map[string]string, migrated map[string]bool) string {
+	renamed := strings.Builder{}
+	for i := 0; i < len(value); {
+		// Copy strings verbatim.
+		if value[i] == '"' {
+			end := i + 1
+			for end < len(value) && (value[end] != '"' || value[end-1] == '\\') {
+				end++
+			}
+			if end < len(value) {
+				end++
+			}
+			renamed.WriteString(value[i:end])
+			i = end
+			continue
+		}
+		if !isIdentifierByte(value[i]) {
+			renamed.WriteByte(value[i])
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(value) && isIdentifierByte(value[end]) {
+			end++
+		}
+		identifier := value[i:end]
+		current, ok := renames[identifier]
+		if ok && (i == 0 || value[i-1] != '.') {
+			migrated[identifier] = true
+			identifier = current
+		}
+		renamed.WriteString(identifier)
+		i = end
+	}
+	return renamed.String()
+}
+
+// isIdentifierByte reports whether the byte may be part of a field name.
+func isIdentifierByte(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Constants for interacting with the IGDB multiquery endpoint.
+	MULTIQUERY_ENDPOINT       = "multiquery"
+	MAX_MULTIQUERY_SUBQUERIES = 10
+)
+
+// subqueryHeaderPattern matches the header of a multiquery sub-query, e.g. query games "Top Games" {.
+var subqueryHeaderPattern = regexp.MustCompile(`query\s+([\w/]+)\s+"([^"]*)"\s*\{`)
+
+// subquery is a single named query within a multiquery.
+type subquery struct {
+	endpoint string
+	name     string
+	text     string
+}
+
+// parseMultiquery splits a multiquery into its sub-queries, validating that their names are unique.
+func parseMultiquery(query string) ([]subquery, error) {
+	subqueries := []subquery{}
+	names := map[string]bool{}
+
+	rest := query
+	for strings.TrimSpace(rest) != "" {
+		header := subqueryHeaderPattern.FindStringSubmatchIndex(rest)
+		if header == nil || strings.TrimSpace(rest[:header[0]]) != "" {
+			return nil, fmt.Errorf("expected a sub-query of the form query <endpoint> \"<name>\" { ... }; near %q", strings.TrimSpace(rest))
+		}
+
+		// Find the closing brace of the sub-query body, ignoring braces within strings.
+		end := -1
+		inString := false
+		for i := header[1]; i < len(rest) && end < 0; i++ {
+			switch {
+			case rest[i] == '"' && rest[i-1] != '\\':
+				inString = !inString
+			case rest[i] == '}' && !inString:
+				end = i + 1
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("sub-query %q is missing its closing brace", rest[header[4]:header[5]])
+		}
+		if strings.HasPrefix(strings.TrimSpace(rest[end:]), ";") {
+			end += strings.Index(rest[end:], ";") + 1
+		}
+
+		sub := subquery{
+			endpoint: rest[header[2]:header[3]],
+			name:     rest[header[4]:header[5]],
+			text:     strings.TrimSpace(rest[header[0]:end]),
+		}
+		if names[sub.name] {
+			return nil, fmt.Errorf("sub-query name %q is used more than once", sub.name)
+		}
+		names[sub.name] = true
+
+		subqueries = append(subqueries, sub)
+		rest = rest[end:]
+	}
+
+	return subqueries, nil
+}
+
+// MultiqueryContext submits a multiquery, splitting it into several requests when it exceeds the
+// sub-query limit and merging their results in order.
+func (d *DatabaseClient) MultiqueryContext(ctx context.Context, query string) (string, error) {
+	subqueries, err := parseMultiquery(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse multiquery: %s", err.Error())
+	}
+	if len(subqueries) <= MAX_MULTIQUERY_SUBQUERIES {
+		return d.QueryContext(ctx, MULTIQUERY_ENDPOINT, query)
+	}
+
+	// Gather the results of every chunk by the names of their sub-queries.
+	named := map[string]json.RawMessage{}
+	for start := 0; start < len(subqueries); start += MAX_MULTIQUERY_SUBQUERIES {
+		end := start + MAX_MULTIQUERY_SUBQUERIES
+		if end > len(subqueries) {
+			end = len(subqueries)
+		}
+
+		texts := []string{}
+		for _, sub := range subqueries[start:end] {
+			texts = append(texts, sub.text)
+		}
+		result, err := d.QueryContext(ctx, MULTIQUERY_ENDPOINT, strings.Join(texts, "\n"))
+		if err != nil {
+			return "", err
+		}
+
+		results, err := decodeMultiqueryResults(result)
+		if err != nil {
+			return "", err
+		}
+		for _, result := range results {
+			named[result.name] = result.raw
+		}
+	}
+
+	// Merge the results in the order of their sub-queries, however the chunks were answered.
+	merged := []json.RawMessage{}
+	for _, sub := range subqueries {
+		result, ok := named[sub.name]
+		if !ok {
+			return "", fmt.Errorf("multiquery result is missing sub-query %q", sub.name)
+		}
+		merged = append(merged, result)
+	}
+	return encodeJSON(merged)
+}
+
+// multiqueryResult is the result of a single named sub-query of a multiquery, e.g.
+// {"name": "Top Games", "result": [...]}, or {"name": "Count", "count": 42} for counts.
+type multiqueryResult struct {
+	name  string
+	value json.RawMessage
+	raw   json.RawMessage
+}
+
+// decodeMultiqueryResults decodes the results of the sub-queries of a multiquery in order.
+func decodeMultiqueryResults(result string) ([]multiqueryResult, error) {
+	elements := []json.RawMessage{}
+	err := json.Unmarshal([]byte(result), &elements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode multiquery result: %s", err.Error())
+	}
+
+	results := []multiqueryResult{}
+	for _, element := range elements {
+		fields := map[string]json.RawMessage{}
+		err = json.Unmarshal(element, &fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode multiquery result: %s", err.Error())
+		}
+		name := ""
+		err = json.Unmarshal(fields["name"], &name)
+		if err != nil {
+			return nil, fmt.Errorf("multiquery result has no name: %s", string(element))
+		}
+		value, ok := fields["result"]
+		if !ok {
+			value = fields["count"]
+		}
+		results = append(results, multiqueryResult{name: name, value: value, raw: element})
+	}
+	return results, nil
+}
+
+// keyMultiqueryByName rewrites the results of a multiquery as an object keyed by the names of
+// their sub-queries, in order, e.g. {"Top Games": [...], "Count": 42}.
+func keyMultiqueryByName(result string) (string, error) {
+	results, err := decodeMultiqueryResults(result)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, result := range results {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(result.name)
+		if err != nil {
+			return "", err
+		}
+		value := result.value
+		if value == nil {
+			value = json.RawMessage("null")
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	indented := &bytes.Buffer{}
+	err = json.Indent(indented, buf.Bytes(), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode multiquery result: %s", err.Error())
+	}
+	return indented.String(), nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// answerMultiquery answers each sub-query of the multiquery with a record naming it, in order or
+// reversed, as chunks answered separately may be merged in any order.
+func answerMultiquery(t *testing.T, query string, reverse bool) string {
+	t.Helper()
+	subqueries, err := parseMultiquery(query)
+	if err != nil {
+		t.Fatalf("failed to parse the multiquery: %s", err.Error())
+	}
+	results := []map[string]interface{}{}
+	for i := range subqueries {
+		if reverse {
+			i = len(subqueries) - 1 - i
+		}
+		results = append(results, map[string]interface{}{
+			"name":   subqueries[i].name,
+			"result": []map[string]string{{"sub_query": subqueries[i].name}},
+		})
+	}
+	answer, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(answer)
+}
+
+func TestMultiqueryChunksAndMerges(t *testing.T) {
+	subqueries := []string{}
+	for i := 0; i < MAX_MULTIQUERY_SUBQUERIES+3; i++ {
+		subqueries = append(subqueries, fmt.Sprintf("query games \"Query %d\" { fields name; where id = %d; };", i, i))
+	}
+	query := strings.Join(subqueries, "\n")
+
+	requests := 0
+	databaseClient, _ := newTestClient(t, parseTestOptions(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read the request body: %s", err.Error())
+		}
+		io.WriteString(w, answerMultiquery(t, string(body), true))
+	}))
+
+	chunked, err := databaseClient.MultiqueryContext(context.Background(), query)
+	if err != nil {
+		t.Fatalf("multiquery failed: %s", err.Error())
+	}
+	if requests != 2 {
+		t.Errorf("expected %d sub-queries to be sent in 2 chunks, sent %d", len(subqueries), requests)
+	}
+
+	keyed, err := keyMultiqueryByName(chunked)
+	if err != nil {
+		t.Fatalf("failed to key the merged results: %s", err.Error())
+	}
+	unchunked, err := keyMultiqueryByName(answerMultiquery(t, query, false))
+	if err != nil {
+		t.Fatalf("failed to key the unchunked results: %s", err.Error())
+	}
+	if keyed != unchunked {
+		t.Errorf("expected the merged results to match the unchunked results\n%s\ngot\n%s", unchunked, keyed)
+	}
+
+	decoded := map[string][]map[string]string{}
+	err = json.Unmarshal([]byte(keyed), &decoded)
+	if err != nil {
+		t.Fatalf("failed to decode the keyed results: %s", err.Error())
+	}
+	for i := range subqueries {
+		name := fmt.Sprintf("Query %d", i)
+		if records := decoded[name]; len(records) != 1 || records[0]["sub_query"] != name {
+			t.Errorf("expected the results of %q keyed by its name, got %v", name, records)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// Suffix of the keys holding the normalized variants of string fields.
+	NORMALIZED_SUFFIX = "_normalized"
+
+	// The fields normalized by default.
+	DEFAULT_NORMALIZE_FIELDS = "name"
+)
+
+// normalizeString lowercases the string and strips its accents, e.g. Pokémon becomes pokemon.
+func normalizeString(s string) string {
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(stripAccents, s)
+	if err != nil {
+		stripped = s
+	}
+	return strings.ToLower(stripped)
+}
+
+// normalizeStrings adds a normalized variant alongside each of the string fields of every object
+// at every level, e.g. name_normalized for name, so that nested records are normalized too.
+func normalizeStrings(value interface{}, fields []string) interface{} {
+	switch nested := value.(type) {
+	case []interface{}:
+		for _, element := range nested {
+			normalizeStrings(element, fields)
+		}
+	case map[string]interface{}:
+		for _, nestedValue := range nested {
+			normalizeStrings(nestedValue, fields)
+		}
+		for _, field := range fields {
+			if s, ok := nested[field].(string); ok {
+				nested[field+NORMALIZED_SUFFIX] = normalizeString(s)
+			}
+		}
+	}
+	return value
+}
+
+// splitFields splits the comma separated fields, ignoring whitespace and empty fields.
+func splitFields(fields string) []string {
+	split := []string{}
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field != "" {
+			split = append(split, field)
+		}
+	}
+	return split
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// The most of an invalid response quoted by -validate-json.
+	MAX_INVALID_JSON_EXCERPT = 80
+)
+
+// needsProcessing reports whether any post-processing of the query result was requested.
+func (o *options) needsProcessing() bool {
+	return o.needsDecoding() || o.pretty || o.compact
+}
+
+// needsDecoding reports whether the requested post-processing requires decoding the query result.
+func (o *options) needsDecoding() bool {
+	return o.flatten || o.format != FORMAT_JSON || o.withMeta || o.single || o.dedupBy != "" || o.fieldsExclude != "" || o.mergeWithFile != "" || o.rename != "" || o.head > 0 || o.tail > 0 || o.stripNulls || o.normalizeStrings || o.humanizeDates || o.pointer != "" || o.withImageURLs || o.sortKeys || o.decodeEnums || o.decodeEnumsInPlace || o.countDistinct != "" || o.summarize != ""
+}
+
+// replacesRecords reports whether the requested post-processing replaces the array of records with
+// another shape, e.g. an envelope, a histogram, CSV or the value at a JSON pointer.
+func (o *options) replacesRecords() bool {
+	return o.format != FORMAT_JSON || o.withMeta || o.single || o.pointer != "" || o.countDistinct != "" || o.summarize != ""
+}
+
+// processResult applies the requested post-processing steps to the JSON query result.
+func processResult(opts *options, meta *resultMeta, result string) (string, error) {
+	if opts.multiqueryByName && meta.endpoint == MULTIQUERY_ENDPOINT {
+		var err error
+		result, err = keyMultiqueryByName(result)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if !opts.needsProcessing() {
+		return result, nil
+	}
+	if !opts.needsDecoding() {
+		return reformatJSON(opts, result)
+	}
+
+	decoded, err := decodeJSON(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode result: %s", err.Error())
+	}
+
+	if opts.dedupBy != "" {
+		decoded = dedupRecords(decoded, opts.dedupBy)
+	}
+
+	if opts.fieldsExclude != "" {
+		decoded = excludeFields(decoded, splitFields(opts.fieldsExclude))
+	}
+
+	if opts.mergeWithFile != "" {
+		local, err := loadMergeRecords(opts.mergeWithFile, opts.mergeKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to load -merge-with-file: %s", err.Error())
+		}
+		decoded = mergeRecords(decoded, local, opts.mergeKey, opts.mergeOverride)
+	}
+
+	if opts.head > 0 || opts.tail > 0 {
+		decoded = sliceResults(decoded, opts.head, opts.tail)
+	}
+
+	if opts.decodeEnums || opts.decodeEnumsInPlace {
+		decoded = decodeEnums(meta.endpoint, decoded, opts.decodeEnumsInPlace)
+	}
+
+	if opts.withImageURLs {
+		decoded = addImageURLs(decoded, opts.imageSize)
+	}
+
+	if opts.normalizeStrings {
+		decoded = normalizeStrings(decoded, splitFields(opts.normalizeFields))
+	}
+
+	if opts.humanizeDates {
+		location, err := parseDateLocation(opts.dateLayout, opts.dateTZ)
+		if err != nil {
+			return "", err
+		}
+		decoded = humanizeDates(decoded, opts.dateLayout, location)
+	}
+
+	if opts.stripNulls {
+		decoded = stripNulls(decoded, opts.stripEmpty)
+	}
+
+	if opts.pointer != "" {
+		decoded, err = resolvePointer(decoded, opts.pointer)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve -pointer: %s", err.Error())
+		}
+	}
+
+	// Profiling the values of a field replaces the result with their histogram.
+	if opts.countDistinct != "" {
+		counts, err := countDistinct(decoded, opts.countDistinct)
+		if err != nil {
+			return "", err
+		}
+		return formatHistogram(opts.countDistinct, counts), nil
+	}
+	if opts.summarize != "" {
+		return summarizeField(decoded, opts.summarize)
+	}
+
+	if opts.flatten {
+		decoded = flattenResult(decoded)
+	}
+
+	// Renaming after flattening lets the flattened keys of nested fields be renamed too.
+	fields := selectedFields(meta.query)
+	if opts.rename != "" {
+		renames, err := parseRenames(opts.rename)
+		if err != nil {
+			return "", err
+		}
+		var unknown []string
+		decoded, unknown = renameKeys(decoded, renames)
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			newLogger(opts.verbose).Printf("Ignored renaming fields missing from the results: %s", strings.Join(unknown, ", "))
+		}
+		fields = renameFieldList(fields, renames)
+	}
+
+	if opts.single {
+		decoded, err = unwrapSingle(decoded)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Re-encoding the decoded objects sorts their keys at every level, so -sort-keys needs no
+	// step of its own beyond forcing the result through processing.
+	if opts.withMeta {
+		return encodeOutputJSON(opts, wrapResult(meta, decoded))
+	}
+	if opts.format == FORMAT_JSON {
+		return encodeOutputJSON(opts, decoded)
+	}
+	return formatResult(opts.format, decoded, fields)
+}
+
+// selectedFields returns the fields selected by the query in order, or none when it selects every
+// field or can't be parsed, e.g. a multiquery.
+func selectedFields(query string) []string {
+	q, err := parseQuery(query)
+	if err != nil {
+		return nil
+	}
+	fields, ok := q.get("fields")
+	if !ok || strings.TrimSpace(fields) == "*" {
+		return nil
+	}
+	return splitFields(fields)
+}
+
+// excludeFields removes the dotted field paths from every record of the result, e.g. summary or
+// cover.url, fanning out over the arrays along each path.
+func excludeFields(result interface{}, fields []string) interface{} {
+	for _, field := range fields {
+		removePath(result, strings.Split(field, "."))
+	}
+	return result
+}
+
+// removePath removes the value at the path within the value, fanning out over arrays.
+func removePath(value interface{}, path []string) {
+	switch nested := value.(type) {
+	case []interface{}:
+		for _, element := range nested {
+			removePath(element, path)
+		}
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(nested, path[0])
+			return
+		}
+		if nestedValue, ok := nested[path[0]]; ok {
+			removePath(nestedValue, path[1:])
+		}
+	}
+}
+
+// unwrapSingle unwraps a result containing exactly one record to the record itself.
+func unwrapSingle(result interface{}) (interface{}, error) {
+	records, ok := result.([]interface{})
+	if !ok {
+		return result, nil
+	}
+	if len(records) != 1 {
+		return nil, fmt.Errorf("-single expected exactly one result but found %d", len(records))
+	}
+	return records[0], nil
+}
+
+// sliceResults keeps only the first head or last tail records of the result, where zero keeps all.
+func sliceResults(result interface{}, head int, tail int) interface{} {
+	records, ok := result.([]interface{})
+	if !ok {
+		return result
+	}
+	if head > 0 && head < len(records) {
+		records = records[:head]
+	}
+	if tail > 0 && tail < len(records) {
+		records = records[len(records)-tail:]
+	}
+	return records
+}
+
+// stripNulls removes the keys with null values from every object at every level, along with those
+// with empty strings, arrays or objects when empty values are stripped too.
+func stripNulls(value interface{}, stripEmpty bool) interface{} {
+	switch nested := value.(type) {
+	case []interface{}:
+		for i, element := range nested {
+			nested[i] = stripNulls(element, stripEmpty)
+		}
+	case map[string]interface{}:
+		for key, nestedValue := range nested {
+			nestedValue = stripNulls(nestedValue, stripEmpty)
+			if nestedValue == nil || (stripEmpty && isEmptyValue(nestedValue)) {
+				delete(nested, key)
+				continue
+			}
+			nested[key] = nestedValue
+		}
+	}
+	return value
+}
+
+// isEmptyValue reports whether the value is an empty string, array or object.
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// validateJSON validates that the result is a JSON document, quoting the start of it when not.
+func validateJSON(result string) error {
+	if json.Valid([]byte(result)) {
+		return nil
+	}
+	excerpt := result
+	if len(excerpt) > MAX_INVALID_JSON_EXCERPT {
+		excerpt = excerpt[:MAX_INVALID_JSON_EXCERPT] + "..."
+	}
+	return fmt.Errorf("the response isn't valid JSON: %q", excerpt)
+}
+
+// decodeJSON decodes an arbitrary JSON document, preserving numbers exactly.
+func decodeJSON(data string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	err := decoder.Decode(&decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+// encodeJSON encodes an arbitrary JSON document as indented JSON.
+func encodeJSON(value interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result: %s", err.Error())
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// encodeCompactJSON encodes an arbitrary JSON document without whitespace.
+func encodeCompactJSON(value interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+
+	err := encoder.Encode(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result: %s", err.Error())
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// encodeOutputJSON encodes the JSON output compactly when -compact is set, otherwise indented.
+func encodeOutputJSON(opts *options, value interface{}) (string, error) {
+	if opts.compact {
+		return encodeCompactJSON(value)
+	}
+	return encodeJSON(value)
+}
+
+// reformatJSON re-indents or compacts the JSON result, preserving the order of its keys.
+func reformatJSON(opts *options, result string) (string, error) {
+	buf := &bytes.Buffer{}
+	var err error
+	if opts.compact {
+		err = json.Compact(buf, []byte(result))
+	} else {
+		err = json.Indent(buf, []byte(strings.TrimSpace(result)), "", "  ")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to reformat result: %s", err.Error())
+	}
+	return buf.String(), nil
+}
+
+// flattenResult flattens each record of the result, or the result itself when it's a single object.
+func flattenResult(result interface{}) interface{} {
+	switch value := result.(type) {
+	case []interface{}:
+		flattened := make([]interface{}, len(value))
+		for i, record := range value {
+			flattened[i] = flattenResult(record)
+		}
+		return flattened
+	case map[string]interface{}:
+		flattened := map[string]interface{}{}
+		flattenInto(flattened, "", value)
+		return flattened
+	default:
+		return value
+	}
+}
+
+// flattenInto writes the nested value into the flattened object using dotted keys, e.g. genres.0.name.
+func flattenInto(flattened map[string]interface{}, key string, value interface{}) {
+	switch nested := value.(type) {
+	case map[string]interface{}:
+		if len(nested) == 0 && key != "" {
+			flattened[key] = nested
+		}
+		for nestedKey, nestedValue := range nested {
+			flattenInto(flattened, joinKey(key, nestedKey), nestedValue)
+		}
+	case []interface{}:
+		if len(nested) == 0 {
+			flattened[key] = nested
+		}
+		for i, nestedValue := range nested {
+			flattenInto(flattened, joinKey(key, fmt.Sprint(i)), nestedValue)
+		}
+	default:
+		flattened[key] = nested
+	}
+}
+
+// joinKey joins a nested key onto its parent key with a dot.
+func joinKey(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
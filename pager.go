@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// Constants for paging the output through an external pager.
+	PAGER_ENV_VAR = "PAGER"
+	DEFAULT_PAGER = "less"
+
+	// The options less is run with unless LESS is set: quit when the output fits on one screen,
+	// pass colors through and leave the output on the screen after quitting.
+	LESS_ENV_VAR      = "LESS"
+	DEFAULT_LESS_OPTS = "FRX"
+)
+
+// usePager reports whether the output should be paged. By default it's paged only when stdout is a
+// terminal, and never when watching, which would block on the pager between runs.
+func usePager(opts *options) bool {
+	if opts.noPager {
+		return false
+	}
+	if opts.pager {
+		return true
+	}
+	return opts.watch == 0 && isTerminal(os.Stdout)
+}
+
+// pagerCommand returns the pager from PAGER, falling back to less, or nil when PAGER is set empty
+// or to cat, which disable paging.
+func pagerCommand() ([]string, error) {
+	command, ok := os.LookupEnv(PAGER_ENV_VAR)
+	if !ok {
+		command = DEFAULT_PAGER
+	}
+	args, err := splitArgs(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", PAGER_ENV_VAR, err.Error())
+	}
+	if len(args) == 0 || args[0] == "cat" {
+		return nil, nil
+	}
+	return args, nil
+}
+
+// printPaged prints the text through the pager when paging, falling back to printing it directly
+// when the pager can't be started so that the output is never lost.
+func printPaged(opts *options, text string) {
+	if !usePager(opts) {
+		writeStdout(text)
+		return
+	}
+
+	args, err := pagerCommand()
+	if err != nil || args == nil {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start the pager with error: %s\n", err.Error())
+		}
+		writeStdout(text)
+		return
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if _, ok := os.LookupEnv(LESS_ENV_VAR); !ok {
+		cmd.Env = append(os.Environ(), LESS_ENV_VAR+"="+DEFAULT_LESS_OPTS)
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start the pager with error: %s\n", err.Error())
+		writeStdout(text)
+		return
+	}
+	// The pager exits unsuccessfully when quit early, which isn't worth reporting.
+	cmd.Wait()
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	// The IGDB returns at most 500 records per request.
+	MAX_QUERY_LIMIT = 500
+
+	// The most pages fetched at once by -parallel-pages when requests aren't rate limited.
+	MAX_PARALLEL_PAGES = 8
+)
+
+// IncompleteResultError is returned along with the records fetched so far when paging fails part way through.
+type IncompleteResultError struct {
+	Err     error
+	Records int
+}
+
+// Error describes how many records were fetched before the failure.
+func (e *IncompleteResultError) Error() string {
+	return fmt.Sprintf("incomplete after %d records: %s", e.Records, e.Err.Error())
+}
+
+// Unwrap returns the error that interrupted paging.
+func (e *IncompleteResultError) Unwrap() error {
+	return e.Err
+}
+
+// fetchAllPages pages through every record matching the query, merging the pages in order. When
+// interrupted after fetching some pages, those pages are returned with an IncompleteResultError.
+func fetchAllPages(ctx context.Context, databaseClient *DatabaseClient, endpoint string, query string) (string, error) {
+	q, offset, err := parsePagedQuery(query)
+	if err != nil {
+		return "", err
+	}
+
+	merged, err := fetchPagesFrom(ctx, databaseClient, endpoint, q, offset, []json.RawMessage{})
+	if err != nil && len(merged) > 0 {
+		return incompleteResult(merged, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return encodeJSON(merged)
+}
+
+// fetchAllPagesInParallel pages through every record matching the query like fetchAllPages, but
+// counts the records first so that the pages may be fetched concurrently. Falls back to fetching
+// the pages in turn when the count is unavailable.
+func fetchAllPagesInParallel(ctx context.Context, databaseClient *DatabaseClient, endpoint string, query string, concurrency int) (string, error) {
+	q, offset, err := parsePagedQuery(query)
+	if err != nil {
+		return "", err
+	}
+
+	count, err := countRecords(ctx, databaseClient, endpoint, q)
+	if err != nil {
+		databaseClient.logger.Printf("Failed to count the records, fetching pages in turn: %s", err.Error())
+		return fetchAllPages(ctx, databaseClient, endpoint, query)
+	}
+
+	offsets := []int{}
+	for pageOffset := offset; pageOffset < count; pageOffset += MAX_QUERY_LIMIT {
+		offsets = append(offsets, pageOffset)
+	}
+	pages := make([][]json.RawMessage, len(offsets))
+	err = runConcurrently(ctx, concurrency, len(offsets), func(ctx context.Context, i int) error {
+		page, err := fetchPage(ctx, databaseClient, endpoint, q, offsets[i])
+		pages[i] = page
+		return err
+	})
+
+	// Merge the pages in order, up to the first that failed.
+	merged := []json.RawMessage{}
+	for _, page := range pages {
+		if page == nil {
+			break
+		}
+		merged = append(merged, page...)
+	}
+	if err != nil && len(merged) > 0 {
+		return incompleteResult(merged, err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Records added since counting follow the last page.
+	if len(pages) > 0 && len(pages[len(pages)-1]) == MAX_QUERY_LIMIT {
+		merged, err = fetchPagesFrom(ctx, databaseClient, endpoint, q, offset+len(merged), merged)
+		if err != nil {
+			return incompleteResult(merged, err)
+		}
+	}
+	return encodeJSON(merged)
+}
+
+// parsePagedQuery parses the query and its starting offset, setting the largest page size allowed.
+func parsePagedQuery(query string) (*apicalypseQuery, int, error) {
+	q, err := parseQuery(query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse query: %s", err.Error())
+	}
+
+	// Start from the query's own offset, if any, using the largest pages allowed.
+	offset := 0
+	if value, ok := q.get("offset"); ok {
+		offset, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid offset %q", value)
+		}
+	}
+	q.set("limit", strconv.Itoa(MAX_QUERY_LIMIT))
+	return q, offset, nil
+}
+
+// fetchPagesFrom fetches the pages in turn from the offset onwards, appending them to those
+// already merged. On failure, the records merged before it are returned along with the error.
+func fetchPagesFrom(ctx context.Context, databaseClient *DatabaseClient, endpoint string, q *apicalypseQuery, offset int, merged []json.RawMessage) ([]json.RawMessage, error) {
+	for {
+		records, err := fetchPage(ctx, databaseClient, endpoint, q, offset)
+		if err != nil {
+			return merged, err
+		}
+		merged = append(merged, records...)
+
+		// A short page is the last page.
+		if len(records) < MAX_QUERY_LIMIT {
+			return merged, nil
+		}
+		offset += len(records)
+	}
+}
+
+// fetchPage fetches the page of records at the offset.
+func fetchPage(ctx context.Context, databaseClient *DatabaseClient, endpoint string, q *apicalypseQuery, offset int) ([]json.RawMessage, error) {
+	// Copy the query, since pages may be fetched concurrently.
+	page := &apicalypseQuery{clauses: append([]queryClause{}, q.clauses...)}
+	page.set("offset", strconv.Itoa(offset))
+	result, err := databaseClient.QueryContext(ctx, endpoint, page.String())
+	if err != nil {
+		return nil, err
+	}
+
+	records := []json.RawMessage{}
+	err = json.Unmarshal([]byte(result), &records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page at offset %d: %s", offset, err.Error())
+	}
+	return records, nil
+}
+
+// countRecords counts the records matching the where and search clauses of the query.
+func countRecords(ctx context.Context, databaseClient *DatabaseClient, endpoint string, q *apicalypseQuery) (int, error) {
+	countQuery := &apicalypseQuery{}
+	for _, keyword := range []string{"search", "where"} {
+		if value, ok := q.get(keyword); ok {
+			countQuery.set(keyword, value)
+		}
+	}
+	result, err := databaseClient.QueryContext(ctx, endpoint+COUNT_ENDPOINT_SUFFIX, countQuery.String())
+	if err != nil {
+		return 0, err
+	}
+
+	count := &struct {
+		Count *int `json:"count"`
+	}{}
+	err = json.Unmarshal([]byte(result), count)
+	if err != nil {
+		return 0, err
+	}
+	if count.Count == nil {
+		return 0, fmt.Errorf("count missing from %s", result)
+	}
+	return *count.Count, nil
+}
+
+// pageConcurrency is the number of pages fetched at once by -parallel-pages, as many as the rate
+// limit can sustain.
+func pageConcurrency(opts *options, credentials int) int {
+	if opts.rateLimit <= 0 {
+		return MAX_PARALLEL_PAGES
+	}
+	return int(math.Ceil(opts.rateLimit * float64(credentials)))
+}
+
+// incompleteResult returns the records fetched before paging was interrupted by the error.
+func incompleteResult(merged []json.RawMessage, err error) (string, error) {
+	partial, encodeErr := encodeJSON(merged)
+	if encodeErr != nil {
+		return "", err
+	}
+	return partial, &IncompleteResultError{Err: err, Records: len(merged)}
+}
+
+// highWaterMark returns the latest updated_at of the records, or zero when there are none.
+func highWaterMark(result string) (int64, error) {
+	records, err := decodeRecords(result)
+	if err != nil {
+		return 0, err
+	}
+
+	var mark int64
+	for _, record := range records {
+		updatedAt, ok := record["updated_at"].(json.Number)
+		if !ok {
+			continue
+		}
+		value, err := updatedAt.Int64()
+		if err == nil && value > mark {
+			mark = value
+		}
+	}
+
+	return mark, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+const (
+	// Commands killed by a signal exit with this plus the signal number, like a shell reports them.
+	SIGNALED_EXIT_CODE_BASE = 128
+)
+
+// pipeError is returned when the command piped through exits unsuccessfully, carrying its exit
+// code, or 128 plus the signal number when it was killed by a signal.
+type pipeError struct {
+	command  string
+	exitCode int
+}
+
+// Error describes the command and its exit code.
+func (e *pipeError) Error() string {
+	return fmt.Sprintf("%q exited with status %d", e.command, e.exitCode)
+}
+
+// formatOutput formats the JSON output with the external formatter of -formatter. The contract of
+// formatters is that they read the JSON results from stdin and print the formatted output to
+// stdout, which is printed in place of the JSON, exiting unsuccessfully when they fail.
+func formatOutput(formatter string, output string) (string, error) {
+	return pipeOutput(formatter, output)
+}
+
+// pipeOutput pipes the output through the external command, returning what it prints to stdout.
+// The command is split into its arguments like a shell would, but run without one, and its stderr
+// is passed through.
+func pipeOutput(command string, output string) (string, error) {
+	args, err := splitArgs(command)
+	if err != nil {
+		return "", fmt.Errorf("invalid command: %s", err.Error())
+	}
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+
+	stdout := &bytes.Buffer{}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(output + "\n")
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", &pipeError{command: command, exitCode: exitCodeOf(exitErr)}
+	}
+	if err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// exitCodeOf returns the exit code of the exited command, or 128 plus the signal number when it
+// was killed by a signal, in place of the -1 reported for it.
+func exitCodeOf(exitErr *exec.ExitError) int {
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if ok && status.Signaled() {
+		return SIGNALED_EXIT_CODE_BASE + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
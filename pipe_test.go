@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestPipeOutputExitCodes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	cases := []struct {
+		name     string
+		command  string
+		exitCode int
+	}{
+		{"exited", `sh -c "exit 3"`, 3},
+		{"killed", `sh -c "kill -TERM $$"`, 143},
+		{"interrupted", `sh -c "kill -INT $$"`, 130},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := pipeOutput(c.command, "[]")
+			var pipeErr *pipeError
+			if !errors.As(err, &pipeErr) {
+				t.Fatalf("expected a pipe error, got %v", err)
+			}
+			if pipeErr.exitCode != c.exitCode {
+				t.Errorf("expected exit code %d, got %d", c.exitCode, pipeErr.exitCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePointer parses an RFC 6901 JSON Pointer into its unescaped reference tokens, e.g.
+// /0/genres/1/name, where the empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New("a JSON pointer must be empty or start with /")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		// Only ~0 and ~1 are valid escapes, for ~ and / respectively.
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid escape in JSON pointer token %q, expected ~0 or ~1", token)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// resolvePointer returns the value the JSON Pointer refers to within the document.
+func resolvePointer(document interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	value := document
+	for i, token := range tokens {
+		location := "the root"
+		if i > 0 {
+			location = "/" + strings.Join(tokens[:i], "/")
+		}
+		switch nested := value.(type) {
+		case map[string]interface{}:
+			member, ok := nested[token]
+			if !ok {
+				return nil, fmt.Errorf("no member %q at %s", token, location)
+			}
+			value = member
+		case []interface{}:
+			index, err := parsePointerIndex(token)
+			if err != nil {
+				return nil, fmt.Errorf("%s at %s", err.Error(), location)
+			}
+			if index >= len(nested) {
+				return nil, fmt.Errorf("index %d is out of range of the %d elements at %s", index, len(nested), location)
+			}
+			value = nested[index]
+		default:
+			return nil, fmt.Errorf("no member %q at %s, which is neither an object nor an array", token, location)
+		}
+	}
+	return value, nil
+}
+
+// parsePointerIndex parses an array index of a JSON Pointer, which is a decimal without leading zeros.
+func parsePointerIndex(token string) (int, error) {
+	if token == "-" {
+		return 0, errors.New("the index - refers past the last element")
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') || strings.HasPrefix(token, "+") {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return index, nil
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clauseAliases maps the shorthand APIcalypse clause keywords to their full names.
+var clauseAliases = map[string]string{
+	"f": "fields",
+	"x": "exclude",
+	"w": "where",
+	"s": "sort",
+	"l": "limit",
+	"o": "offset",
+}
+
+// queryClause is a single clause of an APIcalypse query, e.g. where rating > 80.
+type queryClause struct {
+	keyword string
+	value   string
+}
+
+// apicalypseQuery is a parsed APIcalypse query, preserving the order of its clauses.
+type apicalypseQuery struct {
+	clauses []queryClause
+}
+
+// parseQuery parses an APIcalypse query into its clauses.
+func parseQuery(query string) (*apicalypseQuery, error) {
+	statements, err := splitStatements(query)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &apicalypseQuery{}
+	for _, statement := range statements {
+		keyword, value := statement, ""
+		if i := strings.IndexAny(statement, " \t\r\n"); i >= 0 {
+			keyword, value = statement[:i], strings.TrimSpace(statement[i:])
+		}
+		keyword = strings.ToLower(keyword)
+		if alias, ok := clauseAliases[keyword]; ok {
+			keyword = alias
+		}
+		q.clauses = append(q.clauses, queryClause{keyword: keyword, value: value})
+	}
+
+	return q, nil
+}
+
+// splitStatements splits the query into its semicolon terminated statements, ignoring semicolons within strings.
+func splitStatements(query string) ([]string, error) {
+	statements := []string{}
+	inString := false
+	start := 0
+	for i := 0; i < len(query); i++ {
+		switch {
+		case query[i] == '"' && (i == 0 || query[i-1] != '\\'):
+			inString = !inString
+		case query[i] == ';' && !inString:
+			statements = appendStatement(statements, query[start:i])
+			start = i + 1
+		}
+	}
+	if inString {
+		return nil, errors.New("query contains an unterminated string")
+	}
+
+	return appendStatement(statements, query[start:]), nil
+}
+
+// appendStatement appends the statement, trimmed of surrounding whitespace, when it isn't empty.
+func appendStatement(statements []string, statement string) []string {
+	statement = strings.TrimSpace(statement)
+	if statement == "" {
+		return statements
+	}
+	return append(statements, statement)
+}
+
+// get returns the value of the clause with the given keyword, if present.
+func (q *apicalypseQuery) get(keyword string) (string, bool) {
+	for _, clause := range q.clauses {
+		if clause.keyword == keyword {
+			return clause.value, true
+		}
+	}
+	return "", false
+}
+
+// set sets the value of the clause with the given keyword, appending it when not present.
+func (q *apicalypseQuery) set(keyword string, value string) {
+	for i, clause := range q.clauses {
+		if clause.keyword == keyword {
+			q.clauses[i].value = value
+			return
+		}
+	}
+	q.clauses = append(q.clauses, queryClause{keyword: keyword, value: value})
+}
+
+// addWhere narrows the where clause with the given condition.
+func (q *apicalypseQuery) addWhere(condition string) {
+	where, ok := q.get("where")
+	if !ok {
+		q.set("where", condition)
+		return
+	}
+	q.set("where", fmt.Sprintf("(%s) & %s", where, condition))
+}
+
+// String formats the query as APIcalypse, one clause per line.
+func (q *apicalypseQuery) String() string {
+	lines := []string{}
+	for _, clause := range q.clauses {
+		if clause.value == "" {
+			lines = append(lines, clause.keyword+";")
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s %s;", clause.keyword, clause.value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseSince parses the -since flag as a date, an RFC 3339 timestamp or a unix epoch.
+func parseSince(since string) (int64, error) {
+	epoch, err := strconv.ParseInt(since, 10, 64)
+	if err == nil {
+		return epoch, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		t, err := time.Parse(layout, since)
+		if err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid -since %q, expected a date like 2024-01-01, an RFC 3339 timestamp or a unix epoch", since)
+}
+
+// mergeQueries merges the query over the base query, with the query's clauses replacing the
+// base clauses of the same keyword and the rest following them.
+func mergeQueries(base *apicalypseQuery, q *apicalypseQuery) *apicalypseQuery {
+	merged := &apicalypseQuery{clauses: append([]queryClause{}, base.clauses...)}
+	for _, clause := range q.clauses {
+		merged.set(clause.keyword, clause.value)
+	}
+	return merged
+}
+
+// normalizeQueryWhitespace collapses runs of whitespace outside of string literals to a single space,
+// dropping it entirely at either end and around the separators of clauses and values.
+func normalizeQueryWhitespace(query string) string {
+	normalized := &strings.Builder{}
+	pendingSpace := false
+	var quote, last byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if quote != 0 {
+			normalized.WriteByte(c)
+			if c == '\\' && i+1 < len(query) {
+				i++
+				normalized.WriteByte(query[i])
+			} else if c == quote {
+				quote = 0
+			}
+			last = query[i]
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			pendingSpace = normalized.Len() > 0
+			continue
+		case ';', ',':
+			pendingSpace = false
+		case '"', '\'':
+			quote = c
+		}
+		if pendingSpace && last != ';' && last != ',' {
+			normalized.WriteByte(' ')
+		}
+		pendingSpace = false
+		normalized.WriteByte(c)
+		last = c
+	}
+	return normalized.String()
+}
+
+// prepareQuery applies the query transformations requested via flags.
+func prepareQuery(opts *options, endpoint string, query string) (string, error) {
+	// Substitute values into the query only when templating is explicitly enabled.
+	if opts.template {
+		var err error
+		query, err = renderQueryTemplate(query)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Expand the macros of the config file and relative times, e.g. {recent} and <now-30d>.
+	query, err := expandMacros(query, opts.macros, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	_, hasPreset := defaultFieldPresets[endpoint]
+	hasDefaultFields := hasPreset || opts.fields != "" || opts.allFields || opts.fieldsExclude != ""
+	safeMode := isSafeMode(opts)
+	if opts.since == "" && opts.idsFile == "" && !opts.migrateFields && opts.baseQuery == "" && opts.defaultLimit == 0 && !hasDefaultFields && !safeMode {
+		return query, nil
+	}
+	// The limits of subqueries aren't enforced, so multiqueries are disabled in safe mode.
+	if endpoint == MULTIQUERY_ENDPOINT && safeMode {
+		return "", fmt.Errorf("the %s endpoint is disabled in safe mode", endpoint)
+	}
+	// The default limit is left to the subqueries of multiqueries.
+	if endpoint == MULTIQUERY_ENDPOINT && (opts.since != "" || opts.migrateFields || opts.baseQuery != "") {
+		return "", fmt.Errorf("-since, -migrate-fields and -base-query are not supported for the %s endpoint", endpoint)
+	}
+	if endpoint == MULTIQUERY_ENDPOINT {
+		return query, nil
+	}
+	if opts.since != "" && strings.HasSuffix(endpoint, COUNT_ENDPOINT_SUFFIX) {
+		return "", fmt.Errorf("-since is not supported for the %s endpoint", endpoint)
+	}
+
+	q, err := parseQuery(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse query: %s", err.Error())
+	}
+
+	// Fill in the clauses the query leaves to the base query.
+	if opts.baseQuery != "" {
+		base, err := parseQuery(opts.baseQuery)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse base query: %s", err.Error())
+		}
+		q = mergeQueries(base, q)
+	}
+
+	// Each chunk of -ids-file selects its IDs exactly, so an offset would skip records of every chunk.
+	if _, ok := q.get("offset"); ok && opts.idsFile != "" {
+		return "", errors.New("offset can't be combined with -ids-file, which fetches the records of every ID")
+	}
+
+	// Count endpoints return only the count, whatever the fields.
+	if !strings.HasSuffix(endpoint, COUNT_ENDPOINT_SUFFIX) {
+		applyDefaultFields(opts, endpoint, q)
+	}
+
+	// Make the number of records returned explicit when the query doesn't limit it.
+	if _, ok := q.get("limit"); !ok && opts.defaultLimit > 0 && !strings.HasSuffix(endpoint, COUNT_ENDPOINT_SUFFIX) {
+		q.set("limit", strconv.Itoa(opts.defaultLimit))
+		newLogger(opts.verbose).Printf("Applied the default limit of %d", opts.defaultLimit)
+	}
+
+	if safeMode {
+		err = enforceSafeLimit(opts, q)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Rewrite the deprecated fields of old queries before adding to them.
+	if opts.migrateFields {
+		migrateFields(endpoint, q)
+	}
+
+	// Incremental pulls walk the records updated since the given time in order.
+	if opts.since != "" {
+		since, err := parseSince(opts.since)
+		if err != nil {
+			return "", err
+		}
+		q.addWhere(fmt.Sprintf("updated_at > %d", since))
+		q.set("sort", "updated_at asc")
+	}
+
+	return q.String(), nil
+}
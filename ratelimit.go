@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitHeaders are the names, in order of preference, of the headers reporting each part of the rate limit status.
+var rateLimitHeaders = map[string][]string{
+	"limit":     {"X-RateLimit-Limit", "RateLimit-Limit"},
+	"remaining": {"X-RateLimit-Remaining", "RateLimit-Remaining"},
+	"reset":     {"X-RateLimit-Reset", "RateLimit-Reset"},
+}
+
+// RateLimitStatus is the rate limit status reported by the headers of a response, where each part
+// is nil when not reported.
+type RateLimitStatus struct {
+	Limit     *int64 `json:"limit,omitempty"`
+	Remaining *int64 `json:"remaining,omitempty"`
+	Reset     *int64 `json:"reset,omitempty"`
+}
+
+// parseRateLimitStatus parses the rate limit status from the response headers, or returns nil when none are reported.
+func parseRateLimitStatus(header http.Header) *RateLimitStatus {
+	status := &RateLimitStatus{
+		Limit:     parseRateLimitHeader(header, rateLimitHeaders["limit"]),
+		Remaining: parseRateLimitHeader(header, rateLimitHeaders["remaining"]),
+		Reset:     parseRateLimitHeader(header, rateLimitHeaders["reset"]),
+	}
+	if status.Limit == nil && status.Remaining == nil && status.Reset == nil {
+		return nil
+	}
+	return status
+}
+
+// parseRateLimitHeader parses the first of the headers present as an integer, ignoring malformed values.
+func parseRateLimitHeader(header http.Header, names []string) *int64 {
+	for _, name := range names {
+		value, err := strconv.ParseInt(header.Get(name), 10, 64)
+		if err == nil {
+			return &value
+		}
+	}
+	return nil
+}
+
+// String describes the reported parts of the rate limit status.
+func (s *RateLimitStatus) String() string {
+	parts := []string{}
+	if s.Limit != nil {
+		parts = append(parts, fmt.Sprintf("limit %d", *s.Limit))
+	}
+	if s.Remaining != nil {
+		parts = append(parts, fmt.Sprintf("remaining %d", *s.Remaining))
+	}
+	if s.Reset != nil {
+		parts = append(parts, fmt.Sprintf("reset %d", *s.Reset))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// rateLimiter spaces requests evenly to stay within a number of requests per second.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter instantiates a rate limiter allowing the given requests per second.
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / requestsPerSecond),
+	}
+}
+
+// Wait blocks until the next request may be made, or the context is done.
+func (r *rateLimiter) Wait(ctx context.Context) error {
+	// Reserve the next available slot before waiting for it.
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	slot := r.next
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
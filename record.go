@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// Placeholder for sensitive values omitted from recordings.
+	REDACTED = "<redacted>"
+
+	// Response bodies are recorded up to this many bytes, beyond which they're truncated.
+	MAX_RECORDED_BODY_SIZE = 1 << 20
+
+	// Content type of form encoded bodies, such as those of the Twitch authentication requests.
+	FORM_CONTENT_TYPE = "application/x-www-form-urlencoded"
+)
+
+// sensitiveHeaders are the headers redacted from recordings unless secrets are included.
+var sensitiveHeaders = []string{IGDB_AUTH_TOKEN_HEADER}
+
+// sensitiveBodyFields are the JSON or form body fields redacted from recordings unless secrets are included.
+var sensitiveBodyFields = []string{"client_secret", "access_token", "refresh_token", "device_code"}
+
+// recordedExchange is a single recorded request along with its response or error.
+type recordedExchange struct {
+	Request  recordedRequest   `json:"request"`
+	Response *recordedResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// recordedRequest is the recording of a request.
+type recordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+// recordedResponse is the recording of a response, whose body is truncated beyond MAX_RECORDED_BODY_SIZE.
+type recordedResponse struct {
+	Status    int         `json:"status"`
+	Headers   http.Header `json:"headers"`
+	Body      string      `json:"body"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// recordingTransport records every request and response passing through it to a file, which is
+// rewritten after each exchange so that it's complete even if the program exits early.
+type recordingTransport struct {
+	next           http.RoundTripper
+	path           string
+	includeSecrets bool
+
+	mu        sync.Mutex
+	exchanges []recordedExchange
+}
+
+// newRecordingTransport instantiates a transport recording the exchanges of the next transport to the path.
+func newRecordingTransport(next http.RoundTripper, path string, includeSecrets bool) *recordingTransport {
+	return &recordingTransport{
+		next:           next,
+		path:           path,
+		includeSecrets: includeSecrets,
+	}
+}
+
+// RoundTrip performs the request with the next transport, recording the exchange once the body
+// of the response has been read or closed.
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readAndRestore(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	exchange := recordedExchange{
+		Request: recordedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: r.redactHeaders(req.Header),
+			Body:    r.redactBody(req.Header, reqBody),
+		},
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		exchange.Error = err.Error()
+		r.record(exchange)
+		return nil, err
+	}
+
+	recordResponse := func(body []byte, truncated bool) {
+		exchange.Response = &recordedResponse{
+			Status:    resp.StatusCode,
+			Headers:   r.redactHeaders(resp.Header),
+			Body:      r.redactBody(resp.Header, body),
+			Truncated: truncated,
+		}
+		r.record(exchange)
+	}
+	if resp.Body == nil || resp.Body == http.NoBody {
+		recordResponse(nil, false)
+		return resp, nil
+	}
+	resp.Body = &recordingBody{body: resp.Body, record: recordResponse}
+
+	return resp, nil
+}
+
+// recordingBody tees a response body into a buffer capped at MAX_RECORDED_BODY_SIZE as it's read,
+// recording it once read to the end or closed, so that the response still streams to the reader.
+type recordingBody struct {
+	body      io.ReadCloser
+	buffer    bytes.Buffer
+	truncated bool
+	recorded  sync.Once
+	record    func(body []byte, truncated bool)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.tee(p[:n])
+	if err != nil {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.body.Close()
+	b.finish()
+	return err
+}
+
+// tee buffers as much of what was read as fits under the cap.
+func (b *recordingBody) tee(read []byte) {
+	remaining := MAX_RECORDED_BODY_SIZE - b.buffer.Len()
+	if len(read) > remaining {
+		read = read[:remaining]
+		b.truncated = true
+	}
+	b.buffer.Write(read)
+}
+
+// finish records the buffered body, once.
+func (b *recordingBody) finish() {
+	b.recorded.Do(func() {
+		b.record(b.buffer.Bytes(), b.truncated)
+	})
+}
+
+// record appends the exchange and rewrites the recording, reporting but otherwise ignoring failures.
+func (r *recordingTransport) record(exchange recordedExchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.exchanges = append(r.exchanges, exchange)
+	recording, err := encodeJSON(r.exchanges)
+	if err == nil {
+		err = os.WriteFile(r.path, []byte(recording+"\n"), 0600)
+	}
+	if err != nil {
+		r.exchanges = r.exchanges[:len(r.exchanges)-1]
+		fmt.Fprintf(os.Stderr, "failed to write the recording with error: %s\n", err.Error())
+	}
+}
+
+// redactHeaders copies the headers, redacting the sensitive ones unless secrets are included.
+func (r *recordingTransport) redactHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	if r.includeSecrets {
+		return redacted
+	}
+	for _, header := range sensitiveHeaders {
+		if redacted.Get(header) != "" {
+			redacted.Set(header, REDACTED)
+		}
+	}
+	return redacted
+}
+
+// redactBody redacts the sensitive fields of JSON object and form bodies unless secrets are included.
+func (r *recordingTransport) redactBody(headers http.Header, body []byte) string {
+	if r.includeSecrets {
+		return string(body)
+	}
+	if strings.HasPrefix(headers.Get(IGDB_CONTENT_TYPE_HEADER), FORM_CONTENT_TYPE) {
+		return redactFormBody(body)
+	}
+
+	object := map[string]interface{}{}
+	if json.Unmarshal(body, &object) != nil {
+		return string(body)
+	}
+
+	redacted := false
+	for _, field := range sensitiveBodyFields {
+		if _, ok := object[field]; ok {
+			object[field] = REDACTED
+			redacted = true
+		}
+	}
+	if !redacted {
+		return string(body)
+	}
+
+	redactedBody, err := json.Marshal(object)
+	if err != nil {
+		return REDACTED
+	}
+	return string(redactedBody)
+}
+
+// redactFormBody redacts the sensitive fields of a form body, redacting all of it if it can't be parsed.
+func redactFormBody(body []byte) string {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return REDACTED
+	}
+
+	redacted := false
+	for _, field := range sensitiveBodyFields {
+		if form.Has(field) {
+			form.Set(field, REDACTED)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return string(body)
+	}
+	return form.Encode()
+}
+
+// readAndRestore reads the body in full, replacing it with a reader over the same bytes.
+func readAndRestore(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	bodyBytes, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(bodyBytes))
+	return bodyBytes, nil
+}
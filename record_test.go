@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readRecording reads the exchanges recorded to the path.
+func readRecording(t *testing.T, path string) []recordedExchange {
+	t.Helper()
+	recording, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read the recording: %s", err.Error())
+	}
+	exchanges := []recordedExchange{}
+	err = json.Unmarshal(recording, &exchanges)
+	if err != nil {
+		t.Fatalf("failed to decode the recording: %s", err.Error())
+	}
+	return exchanges
+}
+
+func TestRecordingStreamsResponses(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "[{\"id\":1},\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+		io.WriteString(w, "{\"id\":2}]")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	client := &http.Client{Transport: newRecordingTransport(http.DefaultTransport, path, false)}
+
+	// The first record arrives before the server finishes responding.
+	read := make(chan string, 1)
+	go func() {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			read <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		read <- line
+	}()
+	select {
+	case line := <-read:
+		if line != "[{\"id\":1},\n" {
+			t.Fatalf("expected the first record, read %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the response to stream through the recording")
+	}
+}
+
+func TestRecordingCapsResponseBodies(t *testing.T) {
+	body := strings.Repeat("x", MAX_RECORDED_BODY_SIZE+10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	client := &http.Client{Transport: newRecordingTransport(http.DefaultTransport, path, false)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %s", err.Error())
+	}
+	received, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("failed to read the response: %s", err.Error())
+	}
+	if len(received) != len(body) {
+		t.Errorf("expected the full %d byte response, read %d bytes", len(body), len(received))
+	}
+
+	exchanges := readRecording(t, path)
+	if len(exchanges) != 1 || exchanges[0].Response == nil {
+		t.Fatalf("expected one recorded response, got %+v", exchanges)
+	}
+	recorded := exchanges[0].Response
+	if len(recorded.Body) != MAX_RECORDED_BODY_SIZE || !recorded.Truncated {
+		t.Errorf("expected the body truncated to %d bytes, recorded %d bytes, truncated %t", MAX_RECORDED_BODY_SIZE, len(recorded.Body), recorded.Truncated)
+	}
+}
+
+func TestRecordingRedactsBodies(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		secrets     []string
+	}{
+		{"json token", "application/json", `{"access_token":"a1","refresh_token":"r1","expires_in":3600}`, []string{"a1", "r1"}},
+		{"json device code", "application/json", `{"device_code":"d1","user_code":"ABCD"}`, []string{"d1"}},
+		{"form secret", FORM_CONTENT_TYPE, "client_id=c&client_secret=s1&grant_type=client_credentials", []string{"s1"}},
+		{"form device code", FORM_CONTENT_TYPE + "; charset=utf-8", "client_id=c&device_code=d2&grant_type=urn", []string{"d2"}},
+		{"form refresh token", FORM_CONTENT_TYPE, "grant_type=refresh_token&refresh_token=r2", []string{"r2"}},
+	}
+	recorder := newRecordingTransport(nil, "", false)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			headers := http.Header{IGDB_CONTENT_TYPE_HEADER: {c.contentType}}
+			redacted := recorder.redactBody(headers, []byte(c.body))
+			for _, secret := range c.secrets {
+				if strings.Contains(redacted, secret) {
+					t.Errorf("expected %q to be redacted from %q", secret, redacted)
+				}
+			}
+			if !strings.Contains(redacted, "redacted") {
+				t.Errorf("expected a placeholder in %q", redacted)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Separates the old and new names of a field renamed by -rename.
+	RENAME_SEPARATOR = ":"
+)
+
+// parseRenames parses the comma separated old:new pairs of -rename into the new name of each field.
+func parseRenames(spec string) (map[string]string, error) {
+	renames := map[string]string{}
+	for _, pair := range splitFields(spec) {
+		from, to, ok := strings.Cut(pair, RENAME_SEPARATOR)
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid rename %q, expected old%snew", pair, RENAME_SEPARATOR)
+		}
+		if _, ok := renames[from]; ok {
+			return nil, fmt.Errorf("%s is renamed more than once", from)
+		}
+		renames[from] = to
+	}
+	if len(renames) == 0 {
+		return nil, errors.New("no fields to rename")
+	}
+	return renames, nil
+}
+
+// renameKeys renames the keys of every record in the result, returning the renamed fields
+// found in none of the records.
+func renameKeys(result interface{}, renames map[string]string) (interface{}, []string) {
+	records, ok := result.([]interface{})
+	if !ok {
+		records = []interface{}{result}
+	}
+
+	found := map[string]bool{}
+	for _, record := range records {
+		object, ok := record.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for from, to := range renames {
+			value, ok := object[from]
+			if !ok {
+				continue
+			}
+			delete(object, from)
+			object[to] = value
+			found[from] = true
+		}
+	}
+
+	unknown := []string{}
+	for from := range renames {
+		if !found[from] {
+			unknown = append(unknown, from)
+		}
+	}
+	return result, unknown
+}
+
+// renameFieldList renames the fields of the list, e.g. those ordering the columns of tables.
+func renameFieldList(fields []string, renames map[string]string) []string {
+	renamed := make([]string, len(fields))
+	for i, field := range fields {
+		renamed[i] = field
+		if to, ok := renames[field]; ok {
+			renamed[i] = to
+		}
+	}
+	return renamed
+}
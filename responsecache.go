@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Name of the directory caching responses, within the cache directory.
+	RESPONSE_CACHE_DIR_NAME = "responses"
+)
+
+// cachedResponse is a query result cached along with the request it answered.
+type cachedResponse struct {
+	Endpoint  string    `json:"endpoint"`
+	Query     string    `json:"query"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Result    string    `json:"result"`
+}
+
+// requestHash identifies a request by its endpoint and normalized query, so that it's stable across
+// runs and trivially different formatting of the same query hashes the same.
+func requestHash(endpoint string, query string) string {
+	hash := sha256.Sum256([]byte(endpoint + "\n" + normalizeQueryWhitespace(query)))
+	return hex.EncodeToString(hash[:])
+}
+
+// responseCacheDir returns the path of the directory caching responses.
+func responseCacheDir() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, RESPONSE_CACHE_DIR_NAME), nil
+}
+
+// responseCachePath returns the path of the cached response to the request.
+func responseCachePath(endpoint string, query string) (string, error) {
+	dir, err := responseCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, requestHash(endpoint, query)+".json"), nil
+}
+
+// loadCachedResponse reads the cached response to the request, or returns nil when none is cached.
+func loadCachedResponse(endpoint string, query string) (*cachedResponse, error) {
+	path, err := responseCachePath(endpoint, query)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &cachedResponse{}
+	err = json.Unmarshal(cached, response)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cached response %s: %s", path, err.Error())
+	}
+	return response, nil
+}
+
+// storeCachedResponse caches the result of the request as fetched now.
+func storeCachedResponse(endpoint string, query string, result string) error {
+	path, err := responseCachePath(endpoint, query)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	cached, err := json.Marshal(&cachedResponse{Endpoint: endpoint, Query: query, FetchedAt: time.Now().UTC(), Result: result})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, cached, 0644)
+}
+
+// isFresh reports whether the cached response is younger than the TTL.
+func (c *cachedResponse) isFresh(ttl time.Duration) bool {
+	return time.Since(c.FetchedAt) < ttl
+}
+
+// cacheKey returns the query identifying the result in the response cache, which distinguishes
+// the paged results of -all and -since from the single page of the same query, as fetchResult does.
+func cacheKey(opts *options, query string) string {
+	if opts.all || opts.since != "" {
+		return query + "\npaged"
+	}
+	return query
+}
+
+// cacheResult caches the result, reporting but otherwise ignoring failures.
+func cacheResult(endpoint string, key string, result string) {
+	err := storeCachedResponse(endpoint, key, result)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the response cache with error: %s\n", err.Error())
+	}
+}
+
+// loadStaleResponse returns the expired cached response of the query to serve while revalidating
+// it, or nil when there's none.
+func loadStaleResponse(opts *options, endpoint string, query string) *cachedResponse {
+	cached, err := loadCachedResponse(endpoint, cacheKey(opts, query))
+	if err != nil || cached == nil || cached.isFresh(opts.cache) {
+		return nil
+	}
+	return cached
+}
+
+// runStaleWhileRevalidate handles the result of the expired cached response straight away, as
+// though it was just fetched, then refreshes the cache before exiting. The refresh is synchronous
+// since the program exits once done, so the stale result arrives quickly but the run takes as
+// long as an uncached one. A failed refresh exits unsuccessfully, so scheduled runs notice the
+// cache going stale, as does an assertion about the stale result failing to hold.
+func runStaleWhileRevalidate(ctx context.Context, opts *options, databaseClient *DatabaseClient, endpoint string, query string, stale *cachedResponse) {
+	fmt.Fprintf(os.Stderr, "Serving a stale result fetched at %s, refreshing the cache\n", stale.FetchedAt.Format(time.RFC3339))
+	meta := newResultMeta(endpoint, query)
+	meta.fetchedAt = stale.FetchedAt
+	reportHighWaterMark(opts, stale.Result)
+	assertionErr := handleResult(opts, meta, stale.Result)
+
+	// Refreshing the expired cached response fetches and caches the result as usual.
+	confirmPull(ctx, opts, databaseClient, endpoint, query)
+	_, err := submitQuery(ctx, opts, databaseClient, endpoint, query)
+	if opts.metricsOut != "" {
+		writeMetricsFile(opts.metricsOut, databaseClient)
+	}
+	if err != nil {
+		explainRateLimit(opts, databaseClient)
+		handleQueryErr(opts, "failed to refresh the response cache", err)
+	}
+	if assertionErr != nil {
+		failAssertion(assertionErr)
+	}
+}
+
+// responseCacheStats are the number and size of the cached responses, in total and by endpoint.
+type responseCacheStats struct {
+	dir        string
+	entries    int
+	size       int64
+	byEndpoint map[string]int
+}
+
+// cachedResponseFiles returns the paths of the cached responses, or none when nothing is cached.
+func cachedResponseFiles() (string, []string, error) {
+	dir, err := responseCacheDir()
+	if err != nil {
+		return "", nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return dir, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	paths := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return dir, paths, nil
+}
+
+// readCachedEndpoint returns the endpoint of the cached response at the path.
+func readCachedEndpoint(path string) (string, error) {
+	cached, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	response := &cachedResponse{}
+	err = json.Unmarshal(cached, response)
+	if err != nil {
+		return "", fmt.Errorf("invalid cached response %s: %s", path, err.Error())
+	}
+	return response.Endpoint, nil
+}
+
+// clearResponseCache removes the cached responses of the endpoint, or every cached response when
+// no endpoint is given, returning how many were removed.
+func clearResponseCache(endpoint string) (int, error) {
+	_, paths, err := cachedResponseFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	cleared := 0
+	for _, path := range paths {
+		if endpoint != "" {
+			cachedEndpoint, err := readCachedEndpoint(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to read the response cache with error: %s\n", err.Error())
+				continue
+			}
+			if cachedEndpoint != endpoint {
+				continue
+			}
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return cleared, err
+		}
+		cleared++
+	}
+	return cleared, nil
+}
+
+// statResponseCache counts the cached responses and their size.
+func statResponseCache() (*responseCacheStats, error) {
+	dir, paths, err := cachedResponseFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &responseCacheStats{dir: dir, byEndpoint: map[string]int{}}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		stats.entries++
+		stats.size += info.Size()
+
+		endpoint, err := readCachedEndpoint(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read the response cache with error: %s\n", err.Error())
+			continue
+		}
+		stats.byEndpoint[endpoint]++
+	}
+	return stats, nil
+}
+
+// String formats the stats, followed by the entries of each endpoint.
+func (s *responseCacheStats) String() string {
+	lines := []string{fmt.Sprintf("%d cached responses, %d bytes in %s", s.entries, s.size, s.dir)}
+	endpoints := []string{}
+	for endpoint := range s.byEndpoint {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		lines = append(lines, fmt.Sprintf("  %s\t%d", endpoint, s.byEndpoint[endpoint]))
+	}
+	return strings.Join(lines, "\n")
+}
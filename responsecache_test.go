@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSWRRejectsModesBypassingIt(t *testing.T) {
+	for _, args := range [][]string{
+		{"-cache", "1h", "-swr", "-assert-count", ">1"},
+		{"-cache", "1h", "-swr", "-stream"},
+		{"-cache", "1h", "-swr", "-watch", "30s"},
+	} {
+		err := validateOptions(parseTestOptions(t, args...))
+		if err == nil {
+			t.Errorf("expected %q to be rejected", args)
+		}
+	}
+}
+
+func TestCacheKeyDistinguishesPagedResults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	query := "fields name; limit 500;"
+	single := parseTestOptions(t, "-cache", "1h")
+	cacheResult("games", cacheKey(single, query), `[{"id":1}]`)
+
+	for _, args := range [][]string{
+		{"-cache", "1h", "-all"},
+		{"-cache", "1h", "-since", "2024-01-01"},
+	} {
+		paged := parseTestOptions(t, args...)
+		cached, err := loadCachedResponse("games", cacheKey(paged, query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cached != nil {
+			t.Errorf("expected the single page not to be served for %q", args)
+		}
+	}
+
+	cached, err := loadCachedResponse("games", cacheKey(single, query))
+	if err != nil || cached == nil {
+		t.Errorf("expected the single page to be served without paging, got %v", err)
+	}
+}
+
+func TestHandleResultReturnsFailedAssertions(t *testing.T) {
+	opts := parseTestOptions(t, "-cache", "1h", "-swr", "-max-age", "24h")
+	fetchedAt := time.Now().Add(-48 * time.Hour)
+	meta := newResultMeta("games", "fields name, updated_at;")
+	meta.fetchedAt = fetchedAt
+	stale := fmt.Sprintf(`[{"id":1,"name":"Stale","updated_at":%d}]`, fetchedAt.Unix())
+
+	err := handleResult(opts, meta, stale)
+	var assertionErr *assertionError
+	if !errors.As(err, &assertionErr) {
+		t.Fatalf("expected the stale result to fail -max-age, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// The default retrying of throttled and failed responses.
+	DEFAULT_RETRIES     = 2
+	DEFAULT_RETRY_DELAY = 500 * time.Millisecond
+
+	// The default delay before retrying an empty result with -retry-on-empty.
+	DEFAULT_RETRY_ON_EMPTY_DELAY = 2 * time.Second
+)
+
+// retryJitter randomizes retry delays so that concurrent requests throttled at the same moment
+// don't all retry at the same moment too.
+type retryJitter struct {
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+// newRetryJitter instantiates a jitter source seeded from the current time.
+func newRetryJitter() *retryJitter {
+	return &retryJitter{random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// upTo returns a random duration in [0, max).
+func (j *retryJitter) upTo(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return time.Duration(j.random.Int63n(int64(max)))
+}
+
+// backoff returns the delay before the retry following the given attempt, doubling the base delay
+// each attempt and randomizing the second half of it. A Retry-After header of the response takes
+// precedence, with up to the base delay of jitter added.
+func (j *retryJitter) backoff(resp *http.Response, base time.Duration, attempt int) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err == nil && seconds >= 0 {
+		return time.Duration(seconds)*time.Second + j.upTo(base)
+	}
+
+	delay := base << attempt
+	return delay/2 + j.upTo(delay/2)
+}
+
+// retryBudget caps the retries across every request of the run, where zero is unlimited, so that
+// the auth requests and the queries draw on the same budget.
+type retryBudget struct {
+	limit int
+	mu    sync.Mutex
+	taken int
+}
+
+// take takes a retry from the budget, reporting whether one remained.
+func (b *retryBudget) take(logger *log.Logger) bool {
+	if b.limit <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.taken >= b.limit {
+		logger.Printf("Retry budget of %d exhausted", b.limit)
+		return false
+	}
+	b.taken++
+	logger.Printf("Retry budget: %d of %d remaining", b.limit-b.taken, b.limit)
+	return true
+}
+
+// isRetryableStatus reports whether a response with the status may succeed when retried.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// isEmptyResult reports whether the result is an empty array of records.
+func isEmptyResult(result string) bool {
+	records := []json.RawMessage{}
+	return json.Unmarshal([]byte(result), &records) == nil && len(records) == 0
+}
+
+// sleepContext sleeps for the duration, or until the context is done.
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffIsJittered(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		attempt    int
+		min, max   time.Duration
+	}{
+		{name: "first attempt", attempt: 0, min: 250 * time.Millisecond, max: 500 * time.Millisecond},
+		{name: "third attempt", attempt: 2, min: time.Second, max: 2 * time.Second},
+		{name: "retry after", retryAfter: "3", attempt: 1, min: 3 * time.Second, max: 3*time.Second + 500*time.Millisecond},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jitter := &retryJitter{random: rand.New(rand.NewSource(1))}
+			resp := &http.Response{Header: http.Header{}}
+			if test.retryAfter != "" {
+				resp.Header.Set("Retry-After", test.retryAfter)
+			}
+
+			delays := map[time.Duration]bool{}
+			for i := 0; i < 20; i++ {
+				delay := jitter.backoff(resp, 500*time.Millisecond, test.attempt)
+				if delay < test.min || delay >= test.max {
+					t.Fatalf("expected a delay in [%s, %s), got %s", test.min, test.max, delay)
+				}
+				delays[delay] = true
+			}
+			if len(delays) < 2 {
+				t.Errorf("expected repeated backoffs to differ, all were %v", delays)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	// Set to true to enable -safe-mode for every run, e.g. in shared or demo environments.
+	SAFE_MODE_ENV_VAR = "GAMERS_CONSOLE_SAFE_MODE"
+
+	// The limits enforced by -safe-mode, where limits over the threshold require -yes and those
+	// over the maximum are capped to it.
+	SAFE_MODE_CONFIRM_LIMIT = 50
+	SAFE_MODE_MAX_LIMIT     = 100
+)
+
+// isSafeMode reports whether the program runs in safe mode, either forced with -safe-mode or
+// enabled by the environment, which can't be disabled by flags.
+func isSafeMode(opts *options) bool {
+	if opts.safeMode {
+		return true
+	}
+	safeMode, _ := strconv.ParseBool(os.Getenv(SAFE_MODE_ENV_VAR))
+	return safeMode
+}
+
+// validateSafeMode rejects the options safe mode disables, which could make any number of requests.
+func validateSafeMode(opts *options) error {
+	if !isSafeMode(opts) {
+		return nil
+	}
+	if opts.all || opts.since != "" || opts.idsFile != "" {
+		return errors.New("-all, -since and -ids-file are disabled in safe mode")
+	}
+	if opts.watch > 0 {
+		return errors.New("-watch is disabled in safe mode, since it re-runs the query until interrupted")
+	}
+	// The queries of the helpers are built with limits of their own, bypassing enforceSafeLimit.
+	if opts.gameCompanies != "" || opts.crossRef != "" {
+		return errors.New("-game-companies and -cross-ref are disabled in safe mode")
+	}
+	return nil
+}
+
+// enforceSafeLimit caps the limit of the query in safe mode, requiring -yes for limits over the
+// confirmation threshold.
+func enforceSafeLimit(opts *options, q *apicalypseQuery) error {
+	value, ok := q.get("limit")
+	if !ok {
+		return nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid limit %q", value)
+	}
+	if limit > SAFE_MODE_CONFIRM_LIMIT && !opts.yes {
+		return fmt.Errorf("limits over %d require -yes in safe mode", SAFE_MODE_CONFIRM_LIMIT)
+	}
+	if limit > SAFE_MODE_MAX_LIMIT {
+		q.set("limit", strconv.Itoa(SAFE_MODE_MAX_LIMIT))
+		fmt.Fprintf(os.Stderr, "Capped the limit of %d to %d in safe mode\n", limit, SAFE_MODE_MAX_LIMIT)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestSafeModeDisablesUnboundedRequests(t *testing.T) {
+	cases := []struct {
+		name  string
+		args  []string
+		valid bool
+	}{
+		{"plain query", []string{"-safe-mode"}, true},
+		{"all", []string{"-safe-mode", "-all"}, false},
+		{"since", []string{"-safe-mode", "-since", "2024-01-01"}, false},
+		{"watch", []string{"-safe-mode", "-watch", "30s"}, false},
+		{"game companies", []string{"-safe-mode", "-game-companies", "1942"}, false},
+		{"cross ref", []string{"-safe-mode", "-cross-ref", "steam"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv(SAFE_MODE_ENV_VAR, "")
+			err := validateSafeMode(parseTestOptions(t, c.args...))
+			if (err == nil) != c.valid {
+				t.Errorf("expected %q valid %t, got %v", c.args, c.valid, err)
+			}
+		})
+	}
+}
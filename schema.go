@@ -0,0 +1,104 @@
+package main
+
+import "strings"
+
+// endpointFields is a static schema of the fields of the most common endpoints, excluding the id
+// field every endpoint has. It isn't exhaustive, endpoints missing from it aren't checked.
+var endpointFields = map[string][]string{
+	"age_ratings": {
+		"category", "checksum", "content_descriptions", "organization", "rating", "rating_category",
+		"rating_content_descriptions", "rating_cover_url", "synopsis",
+	},
+	"artworks": {
+		"alpha_channel", "animated", "checksum", "game", "height", "image_id", "url", "width",
+	},
+	"companies": {
+		"change_date", "change_date_category", "change_date_format", "changed_company_id", "checksum", "country",
+		"created_at", "description", "developed", "logo", "name", "parent", "published", "slug", "start_date",
+		"start_date_category", "start_date_format", "status", "updated_at", "url", "websites",
+	},
+	"covers": {
+		"alpha_channel", "animated", "checksum", "game", "game_localization", "height", "image_id", "url", "width",
+	},
+	"external_games": {
+		"category", "checksum", "countries", "created_at", "external_game_source", "game", "game_release_format",
+		"media", "name", "platform", "uid", "updated_at", "url", "year",
+	},
+	"games": {
+		"age_ratings", "aggregated_rating", "aggregated_rating_count", "alternative_names", "artworks", "bundles",
+		"category", "checksum", "collection", "collections", "cover", "created_at", "dlcs", "expanded_games",
+		"expansions", "external_games", "first_release_date", "follows", "forks", "franchise", "franchises",
+		"game_engines", "game_localizations", "game_modes", "game_status", "game_type", "genres", "hypes",
+		"involved_companies", "keywords", "language_supports", "multiplayer_modes", "name", "parent_game",
+		"platforms", "player_perspectives", "ports", "rating", "rating_count", "release_dates", "remakes",
+		"remasters", "screenshots", "similar_games", "slug", "standalone_expansions", "status", "storyline",
+		"summary", "tags", "themes", "total_rating", "total_rating_count", "updated_at", "url", "version_parent",
+		"version_title", "videos", "websites",
+	},
+	"game_localizations": {
+		"checksum", "cover", "created_at", "game", "name", "region", "updated_at",
+	},
+	"game_modes": {
+		"checksum", "created_at", "name", "slug", "updated_at", "url",
+	},
+	"genres": {
+		"checksum", "created_at", "name", "slug", "updated_at", "url",
+	},
+	"involved_companies": {
+		"checksum", "company", "created_at", "developer", "game", "porting", "publisher", "supporting", "updated_at",
+	},
+	"keywords": {
+		"checksum", "created_at", "name", "slug", "updated_at", "url",
+	},
+	"languages": {
+		"checksum", "created_at", "locale", "name", "native_name", "updated_at",
+	},
+	"platforms": {
+		"abbreviation", "alternative_name", "category", "checksum", "created_at", "generation", "name",
+		"platform_family", "platform_logo", "platform_type", "slug", "summary", "updated_at", "url", "versions",
+		"websites",
+	},
+	"player_perspectives": {
+		"checksum", "created_at", "name", "slug", "updated_at", "url",
+	},
+	"regions": {
+		"category", "checksum", "created_at", "identifier", "name", "updated_at",
+	},
+	"release_dates": {
+		"category", "checksum", "created_at", "date", "date_format", "game", "human", "m", "platform", "region",
+		"release_region", "status", "updated_at", "y",
+	},
+	SEARCH_ENDPOINT: {
+		"alternative_name", "character", "checksum", "collection", "company", "description", "game", "name",
+		"platform", "published_at", "test_dummy", "theme",
+	},
+	"screenshots": {
+		"alpha_channel", "animated", "checksum", "game", "height", "image_id", "url", "width",
+	},
+	"themes": {
+		"checksum", "created_at", "name", "slug", "updated_at", "url",
+	},
+	"websites": {
+		"category", "checksum", "game", "trusted", "type", "url",
+	},
+}
+
+// isKnownField reports whether the endpoint has the field, judging nested fields such as
+// platforms.name by their top level field, and whether the endpoint is in the schema at all.
+func isKnownField(endpoint string, field string) (known bool, inSchema bool) {
+	fields, ok := endpointFields[strings.TrimSuffix(endpoint, COUNT_ENDPOINT_SUFFIX)]
+	if !ok {
+		return false, false
+	}
+
+	field = strings.SplitN(field, ".", 2)[0]
+	if field == "id" || field == "*" {
+		return true, true
+	}
+	for _, known := range fields {
+		if field == known {
+			return true, true
+		}
+	}
+	return false, true
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// The endpoint searching across games, companies, characters and more.
+	SEARCH_ENDPOINT = "search"
+
+	// Key added to each search result naming the kind of record it references.
+	SEARCH_RESULT_TYPE_KEY = "type"
+)
+
+// searchResultTypes are the fields of a search result referencing the record it matched, only one
+// of which is set per result.
+var searchResultTypes = []string{"character", "collection", "company", "game", "platform", "theme"}
+
+// globalSearchQuery builds the query searching every kind of record for the term.
+func globalSearchQuery(term string) string {
+	q := &apicalypseQuery{}
+	q.set("search", strconv.Quote(term))
+	q.set("fields", "name,alternative_name,description,published_at,character,collection,company,game,platform,theme")
+	return q.String()
+}
+
+// labelSearchResults labels each search result with the kind of record it references, since unlike
+// other endpoints the results mix several kinds of record.
+func labelSearchResults(result string) (string, error) {
+	records, err := decodeRecords(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode search results: %s", err.Error())
+	}
+
+	for _, record := range records {
+		for _, kind := range searchResultTypes {
+			if record[kind] != nil {
+				record[SEARCH_RESULT_TYPE_KEY] = kind
+				break
+			}
+		}
+	}
+	return encodeJSON(records)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// The smallest query possible, used to check each endpoint responds.
+	SMOKE_QUERY = "fields id; limit 1;"
+)
+
+// smokeEndpoints are the endpoints checked by -smoke.
+var smokeEndpoints = []string{"games", "platforms", "genres"}
+
+// runSmoke submits a minimal query to each of the smoke endpoints in turn, reporting whether each
+// passed and exiting unsuccessfully when any failed.
+func runSmoke(opts *options, httpClient *http.Client) {
+	if len(opts.args) > 0 {
+		handleErr("failed to run the smoke test", errors.New("-smoke can't be combined with a positional endpoint or query"), BAD_USAGE_EXIT_CODE)
+	}
+
+	ctx, cancel := newRunContext(opts)
+	defer cancel()
+	databaseClient := newDatabaseClient(ctx, opts, httpClient)
+
+	// The queries are submitted one at a time, so the rate limit is respected as usual.
+	var firstErr error
+	passed := 0
+	for _, endpoint := range smokeEndpoints {
+		start := time.Now()
+		_, err := databaseClient.QueryContext(ctx, endpoint, SMOKE_QUERY)
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			printfStdout("%s\t%s\t%s\t%s\n", colorize(opts, ANSI_RED, "FAIL"), endpoint, elapsed, describeQueryErr(opts, err).Error())
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		printfStdout("%s\t%s\t%s\n", colorize(opts, ANSI_GREEN, "PASS"), endpoint, elapsed)
+		passed++
+	}
+
+	printfStdout("%d of %d endpoints passed\n", passed, len(smokeEndpoints))
+	if firstErr != nil {
+		explainRateLimit(opts, databaseClient)
+		os.Exit(exitCodeFor(firstErr))
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// querySpec is a structured description of a query, read as JSON by -query-stdin-json.
+type querySpec struct {
+	Endpoint string   `json:"endpoint"`
+	Fields   []string `json:"fields"`
+	Exclude  []string `json:"exclude"`
+	Search   string   `json:"search"`
+	Where    string   `json:"where"`
+	Sort     string   `json:"sort"`
+	Limit    *int     `json:"limit"`
+	Offset   *int     `json:"offset"`
+}
+
+// readQuerySpec reads and validates a JSON query spec, returning its endpoint and APIcalypse query.
+func readQuerySpec(r io.Reader) (string, string, error) {
+	specBytes, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Reject unknown keys so that misspelled clauses aren't silently dropped.
+	spec := &querySpec{}
+	decoder := json.NewDecoder(bytes.NewReader(specBytes))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(spec)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid query spec: %s", err.Error())
+	}
+
+	query, err := spec.build()
+	if err != nil {
+		return "", "", fmt.Errorf("invalid query spec: %s", err.Error())
+	}
+	return spec.Endpoint, query, nil
+}
+
+// build validates the spec and formats it as an APIcalypse query.
+func (s *querySpec) build() (string, error) {
+	if s.Endpoint == "" {
+		return "", errors.New(`"endpoint" is required`)
+	}
+	if len(s.Fields) == 0 {
+		return "", errors.New(`"fields" must list at least one field`)
+	}
+	for _, field := range append(append([]string{}, s.Fields...), s.Exclude...) {
+		if strings.TrimSpace(field) == "" {
+			return "", errors.New("field names must not be empty")
+		}
+	}
+	if s.Limit != nil && (*s.Limit < 1 || *s.Limit > MAX_QUERY_LIMIT) {
+		return "", fmt.Errorf(`"limit" must be between 1 and %d`, MAX_QUERY_LIMIT)
+	}
+	if s.Offset != nil && *s.Offset < 0 {
+		return "", errors.New(`"offset" must not be negative`)
+	}
+
+	q := &apicalypseQuery{}
+	q.set("fields", strings.Join(s.Fields, ","))
+	if len(s.Exclude) > 0 {
+		q.set("exclude", strings.Join(s.Exclude, ","))
+	}
+	if s.Search != "" {
+		q.set("search", strconv.Quote(s.Search))
+	}
+	if s.Where != "" {
+		q.set("where", s.Where)
+	}
+	if s.Sort != "" {
+		q.set("sort", s.Sort)
+	}
+	if s.Limit != nil {
+		q.set("limit", strconv.Itoa(*s.Limit))
+	}
+	if s.Offset != nil {
+		q.set("offset", strconv.Itoa(*s.Offset))
+	}
+	return q.String(), nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Name of the manifest listing the parts written by -split-size and -split-bytes.
+	SPLIT_MANIFEST_FILE_NAME = "manifest.json"
+
+	// Pattern matching the names of the parts, as named by splitPartName.
+	SPLIT_PART_PATTERN = "part-*.ndjson"
+)
+
+// byteSizeUnits are the units of byte sizes, from the largest so that suffixes match greedily.
+var byteSizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// splitPart describes a part of the results written by -split-size or -split-bytes.
+type splitPart struct {
+	File    string `json:"file"`
+	Records int    `json:"records"`
+	Bytes   int    `json:"bytes"`
+}
+
+// parseByteSize parses a size in bytes with an optional unit, e.g. 50MB, 512KB or 1024.
+func parseByteSize(size string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(size))
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(trimmed, unit.suffix) {
+			trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, unit.suffix))
+			multiplier = unit.bytes
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid size %q, expected a positive number of bytes with an optional unit, e.g. 50MB", size)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// splitPartName names the part by its index, e.g. part-00001.ndjson.
+func splitPartName(index int) string {
+	return fmt.Sprintf("part-%05d.ndjson", index)
+}
+
+// writeSplitParts writes the records of the result to the directory as NDJSON parts, rolling over
+// to the next part once it holds the most records or bytes allowed, where zero is unlimited,
+// along with a manifest of the parts. A single record larger than the limit of bytes fills a part
+// of its own. The parts of a previous split to the directory are removed first, so none outlive
+// the manifest listing them.
+func writeSplitParts(dir string, result string, maxRecords int, maxBytes int64) ([]splitPart, error) {
+	records := []json.RawMessage{}
+	err := json.Unmarshal([]byte(result), &records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the records: %s", err.Error())
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	err = removeSplitParts(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := []splitPart{}
+	part := &bytes.Buffer{}
+	partRecords := 0
+	flush := func() error {
+		if partRecords == 0 {
+			return nil
+		}
+		name := splitPartName(len(parts) + 1)
+		err := os.WriteFile(filepath.Join(dir, name), part.Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, splitPart{File: name, Records: partRecords, Bytes: part.Len()})
+		part.Reset()
+		partRecords = 0
+		return nil
+	}
+
+	line := &bytes.Buffer{}
+	for _, record := range records {
+		line.Reset()
+		err = json.Compact(line, record)
+		if err != nil {
+			return nil, err
+		}
+		line.WriteByte('\n')
+
+		full := maxRecords > 0 && partRecords >= maxRecords
+		overflowing := maxBytes > 0 && int64(part.Len()+line.Len()) > maxBytes
+		if full || overflowing {
+			err = flush()
+			if err != nil {
+				return nil, err
+			}
+		}
+		part.Write(line.Bytes())
+		partRecords++
+	}
+	err = flush()
+	if err != nil {
+		return nil, err
+	}
+
+	manifestJSON, err := encodeJSON(parts)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(filepath.Join(dir, SPLIT_MANIFEST_FILE_NAME), []byte(manifestJSON+"\n"), 0644)
+	if err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// removeSplitParts removes the parts previously written to the directory.
+func removeSplitParts(dir string) error {
+	stale, err := filepath.Glob(filepath.Join(dir, SPLIT_PART_PATTERN))
+	if err != nil {
+		return err
+	}
+	for _, path := range stale {
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runSplit post-processes the result as it would be printed, then writes its records to
+// -output-dir in parts split by -split-size and -split-bytes, reporting what was written.
+func runSplit(opts *options, meta *resultMeta, result string) {
+	var maxBytes int64
+	if opts.splitBytes != "" {
+		var err error
+		maxBytes, err = parseByteSize(opts.splitBytes)
+		if err != nil {
+			handleErr("failed to split the results", err, BAD_USAGE_EXIT_CODE)
+		}
+	}
+
+	result, err := processResult(opts, meta, result)
+	if err != nil {
+		handleErr("failed to process the query result", err, INTERNAL_ERROR_EXIT_CODE)
+	}
+	parts, err := writeSplitParts(opts.outputDir, result, opts.splitSize, maxBytes)
+	if err != nil {
+		handleErr("failed to split the results", err, INTERNAL_ERROR_EXIT_CODE)
+	}
+	records := 0
+	for _, part := range parts {
+		records += part.Records
+	}
+	printfStdout("Wrote %d records in %d parts and %s to %s\n", records, len(parts), SPLIT_MANIFEST_FILE_NAME, opts.outputDir)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// readSplitParts returns the names and contents of the parts in the directory.
+func readSplitParts(t *testing.T, dir string) ([]string, string) {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, SPLIT_PART_PATTERN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	contents := &strings.Builder{}
+	for i, name := range names {
+		part, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents.Write(part)
+		names[i] = filepath.Base(name)
+	}
+	return names, contents.String()
+}
+
+func TestWriteSplitPartsRemovesStaleParts(t *testing.T) {
+	dir := t.TempDir()
+	_, err := writeSplitParts(dir, `[{"id":1},{"id":2},{"id":3}]`, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := writeSplitParts(dir, `[{"id":4}]`, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, contents := readSplitParts(t, dir)
+	if len(parts) != 1 || len(names) != 1 || names[0] != splitPartName(1) {
+		t.Errorf("expected only the part of the re-run, found %q", names)
+	}
+	if contents != "{\"id\":4}\n" {
+		t.Errorf("expected the records of the re-run, found %q", contents)
+	}
+}
+
+func TestRunSplitProcessesTheResult(t *testing.T) {
+	dir := t.TempDir()
+	opts := parseTestOptions(t, "-output-dir", dir, "-split-size", "1", "-head", "2", "-fields-exclude", "summary")
+	meta := newResultMeta("games", "fields name, summary;")
+
+	runSplit(opts, meta, `[{"id":1,"name":"A","summary":"a"},{"id":2,"name":"B","summary":"b"},{"id":3,"name":"C","summary":"c"}]`)
+
+	names, contents := readSplitParts(t, dir)
+	if len(names) != 2 {
+		t.Errorf("expected -head to leave 2 parts, found %q", names)
+	}
+	if strings.Contains(contents, "summary") || strings.Contains(contents, `"C"`) {
+		t.Errorf("expected the parts to be post-processed, found %q", contents)
+	}
+}
+
+func TestSplitRejectsNonRecordOutput(t *testing.T) {
+	for _, args := range [][]string{
+		{"-output-dir", "parts", "-split-size", "10", "-format", "csv"},
+		{"-output-dir", "parts", "-split-size", "10", "-with-meta"},
+		{"-output-dir", "parts", "-split-bytes", "1MB", "-summarize", "rating"},
+		{"-output-dir", "parts", "-split-size", "10", "-pointer", "/0/name"},
+	} {
+		err := validateOptions(parseTestOptions(t, args...))
+		if err == nil {
+			t.Errorf("expected %q to be rejected", args)
+		}
+	}
+}
+
+func TestSplitAcceptsRecordOutput(t *testing.T) {
+	err := validateOptions(parseTestOptions(t, "-output-dir", "parts", "-split-size", "10", "-head", "5"))
+	if err != nil {
+		t.Errorf("expected splitting post-processed records to be accepted: %s", err.Error())
+	}
+}
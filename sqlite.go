@@ -0,0 +1,192 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	// The pure Go SQLite driver, avoiding any need for cgo.
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// Constants for writing results to SQLite.
+	SQLITE_DRIVER    = "sqlite"
+	SQLITE_ID_COLUMN = "id"
+)
+
+// writeSQLite writes the flattened records of the result as rows of a table named after the
+// endpoint, creating the table and any missing columns, and upserting rows by their id.
+func writeSQLite(path string, endpoint string, result string) (int, error) {
+	if endpoint == MULTIQUERY_ENDPOINT || strings.HasSuffix(endpoint, COUNT_ENDPOINT_SUFFIX) {
+		return 0, fmt.Errorf("-sqlite is not supported for the %s endpoint", endpoint)
+	}
+
+	decoded, err := decodeJSON(result)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode result: %s", err.Error())
+	}
+	flattened, ok := flattenResult(decoded).([]interface{})
+	if !ok {
+		return 0, fmt.Errorf("expected an array of records")
+	}
+
+	// Collect the union of the keys, every record must have an id to upsert by.
+	seen := map[string]bool{}
+	columns := []string{}
+	for i, record := range flattened {
+		object, ok := record.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("record at index %d is not an object", i)
+		}
+		if _, ok := object[SQLITE_ID_COLUMN]; !ok {
+			return 0, fmt.Errorf("record at index %d has no %s to upsert by", i, SQLITE_ID_COLUMN)
+		}
+		for key := range object {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	db, err := sql.Open(SQLITE_DRIVER, path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = ensureTable(tx, endpoint, columns)
+	if err != nil {
+		return 0, err
+	}
+	if len(flattened) > 0 {
+		err = upsertRows(tx, endpoint, columns, flattened)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(flattened), tx.Commit()
+}
+
+// ensureTable creates the table when it doesn't exist, and adds any of the columns it's missing.
+func ensureTable(tx *sql.Tx, table string, columns []string) error {
+	_, err := tx.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s INTEGER PRIMARY KEY)", quoteIdentifier(table), quoteIdentifier(SQLITE_ID_COLUMN)))
+	if err != nil {
+		return fmt.Errorf("failed to create table %s: %s", table, err.Error())
+	}
+
+	rows, err := tx.Query(fmt.Sprintf("SELECT name FROM pragma_table_info(%s)", quoteString(table)))
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	// Columns are untyped, SQLite keeps the type of each value as written.
+	for _, column := range columns {
+		if existing[column] {
+			continue
+		}
+		_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdentifier(table), quoteIdentifier(column)))
+		if err != nil {
+			return fmt.Errorf("failed to add column %s: %s", column, err.Error())
+		}
+	}
+	return nil
+}
+
+// upsertRows inserts the records as rows of the table, updating the rows with the same id.
+func upsertRows(tx *sql.Tx, table string, columns []string, records []interface{}) error {
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	updates := []string{}
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+		placeholders[i] = "?"
+		if column != SQLITE_ID_COLUMN {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", quoted[i], quoted[i]))
+		}
+	}
+
+	statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT(%s) DO ",
+		quoteIdentifier(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", "), quoteIdentifier(SQLITE_ID_COLUMN))
+	if len(updates) == 0 {
+		statement += "NOTHING"
+	} else {
+		statement += "UPDATE SET " + strings.Join(updates, ", ")
+	}
+	stmt, err := tx.Prepare(statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, record := range records {
+		object := record.(map[string]interface{})
+		values := make([]interface{}, len(columns))
+		for j, column := range columns {
+			values[j], err = sqliteValue(object[column])
+			if err != nil {
+				return err
+			}
+		}
+		_, err = stmt.Exec(values...)
+		if err != nil {
+			return fmt.Errorf("failed to write record at index %d: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+// sqliteValue converts a flattened JSON value to the value written to SQLite, where numbers keep
+// their integer or real type and the remaining nested values are written as JSON text.
+func sqliteValue(value interface{}) (interface{}, error) {
+	switch cell := value.(type) {
+	case nil, string, bool:
+		return cell, nil
+	case json.Number:
+		if integer, err := cell.Int64(); err == nil {
+			return integer, nil
+		}
+		return cell.Float64()
+	default:
+		cellBytes, err := json.Marshal(cell)
+		if err != nil {
+			return nil, err
+		}
+		return string(cellBytes), nil
+	}
+}
+
+// quoteIdentifier quotes a table or column name for use in SQL.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteString quotes a string literal for use in SQL.
+func quoteString(value string) string {
+	return `'` + strings.ReplaceAll(value, `'`, `''`) + `'`
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteWritesTheProcessedResult(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.db")
+	opts := parseTestOptions(t, "-sqlite", path, "-head", "2", "-fields-exclude", "summary")
+	meta := newResultMeta("games", "fields name, summary;")
+
+	err := handleResult(opts, meta, `[{"id":1,"name":"A","summary":"a"},{"id":2,"name":"B","summary":"b"},{"id":3,"name":"C","summary":"c"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open(SQLITE_DRIVER, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows := 0
+	err = db.QueryRow(`SELECT COUNT(*) FROM games`).Scan(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 2 {
+		t.Errorf("expected -head to leave 2 rows, found %d", rows)
+	}
+	_, err = db.Exec(`SELECT summary FROM games`)
+	if err == nil {
+		t.Error("expected -fields-exclude to leave no summary column")
+	}
+}
+
+func TestSQLiteRejectsNonRecordOutput(t *testing.T) {
+	for _, args := range [][]string{
+		{"-sqlite", "games.db", "-format", "csv"},
+		{"-sqlite", "games.db", "-single"},
+		{"-sqlite", "games.db", "-count-distinct", "genres"},
+		{"-sqlite", "games.db", "-pointer", "/0/name"},
+	} {
+		err := validateOptions(parseTestOptions(t, args...))
+		if err == nil {
+			t.Errorf("expected %q to be rejected", args)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runStream submits the query and prints each record as a line of NDJSON as soon as it's decoded,
+// without holding the whole response in memory.
+func runStream(ctx context.Context, opts *options, databaseClient *DatabaseClient, endpoint string, query string) {
+	if endpoint == MULTIQUERY_ENDPOINT || strings.HasSuffix(endpoint, COUNT_ENDPOINT_SUFFIX) {
+		handleErr("failed to stream the query result", fmt.Errorf("-stream is not supported for the %s endpoint", endpoint), BAD_USAGE_EXIT_CODE)
+	}
+
+	writer := bufio.NewWriter(os.Stdout)
+
+	line := &bytes.Buffer{}
+	err := databaseClient.QueryStream(ctx, endpoint, query, func(record json.RawMessage) error {
+		line.Reset()
+		err := json.Compact(line, record)
+		if err != nil {
+			return err
+		}
+		line.WriteByte('\n')
+		_, err = writer.Write(line.Bytes())
+		return err
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	exitOnBrokenPipe(err)
+	if err != nil {
+		writer.Flush()
+		explainRateLimit(opts, databaseClient)
+		handleQueryErr(opts, "failed to stream the query result", err)
+	}
+}
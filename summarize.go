@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// The most frequent values summarized for fields that aren't numeric.
+	SUMMARY_TOP_VALUES = 10
+)
+
+// summarizeField summarizes the values of the dotted field path across the records of the result:
+// the count, min, max, mean and median of numeric fields, or the most frequent values otherwise.
+// Records missing the field, or holding null, are counted as missing.
+func summarizeField(result interface{}, path string) (string, error) {
+	records, ok := result.([]interface{})
+	if !ok {
+		records = []interface{}{result}
+	}
+
+	values := []interface{}{}
+	missing := 0
+	numeric := true
+	for _, record := range records {
+		resolved := resolvePath(record, strings.Split(path, "."))
+		if len(resolved) == 0 {
+			missing++
+		}
+		for _, value := range resolved {
+			if _, ok := value.(json.Number); !ok {
+				numeric = false
+			}
+			values = append(values, value)
+		}
+	}
+
+	header := fmt.Sprintf("%s: %d values, %d missing", path, len(values), missing)
+	if len(values) == 0 {
+		return header, nil
+	}
+	if !numeric {
+		tally := map[string]int{}
+		for _, value := range values {
+			cell, err := formatCell(value)
+			if err != nil {
+				return "", err
+			}
+			tally[cell]++
+		}
+		counts := sortCounts(tally)
+		header = fmt.Sprintf("%s, %d distinct", header, len(counts))
+		if len(counts) > SUMMARY_TOP_VALUES {
+			header = fmt.Sprintf("%s, the %d most frequent of which follow", header, SUMMARY_TOP_VALUES)
+			counts = counts[:SUMMARY_TOP_VALUES]
+		}
+		return header + "\n" + formatHistogramLines(counts), nil
+	}
+
+	numbers := make([]float64, len(values))
+	sum := 0.0
+	for i, value := range values {
+		number, err := value.(json.Number).Float64()
+		if err != nil {
+			return "", fmt.Errorf("invalid number %s of %s: %s", value, path, err.Error())
+		}
+		numbers[i] = number
+		sum += number
+	}
+	sort.Float64s(numbers)
+
+	median := numbers[len(numbers)/2]
+	if len(numbers)%2 == 0 {
+		median = (numbers[len(numbers)/2-1] + median) / 2
+	}
+	lines := []string{
+		header,
+		fmt.Sprintf("min\t%g", numbers[0]),
+		fmt.Sprintf("max\t%g", numbers[len(numbers)-1]),
+		fmt.Sprintf("mean\t%g", sum/float64(len(numbers))),
+		fmt.Sprintf("median\t%g", median),
+	}
+	return strings.Join(lines, "\n"), nil
+}
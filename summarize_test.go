@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeFieldOfStrings(t *testing.T) {
+	records := []string{}
+	for i := 0; i < 12; i++ {
+		for j := 0; j <= i; j++ {
+			records = append(records, fmt.Sprintf(`{"name":"game %d"}`, i))
+		}
+	}
+	records = append(records, `{"id":1}`)
+	decoded, err := decodeJSON("[" + strings.Join(records, ",") + "]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := summarizeField(decoded, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(summary, "\n")
+	expected := fmt.Sprintf("name: 78 values, 1 missing, 12 distinct, the %d most frequent of which follow", SUMMARY_TOP_VALUES)
+	if lines[0] != expected {
+		t.Errorf("expected the header %q, got %q", expected, lines[0])
+	}
+	if len(lines) != SUMMARY_TOP_VALUES+1 {
+		t.Errorf("expected a line for each of the %d most frequent values beneath the header, got:\n%s", SUMMARY_TOP_VALUES, summary)
+	}
+	if strings.Contains(summary, "distinct values of") {
+		t.Errorf("expected a single header, got:\n%s", summary)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(lines[1]), "12  game 11") {
+		t.Errorf("expected the most frequent value first, got %q", lines[1])
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// queryTemplateFuncs are the functions available to query templates.
+var queryTemplateFuncs = template.FuncMap{
+	"env": templateEnv,
+}
+
+// templateEnv returns the value of the environment variable, or the default when it's unset. An
+// unset variable without a default is an error, so that queries aren't silently sent without it.
+func templateEnv(name string, defaults ...string) (string, error) {
+	if len(defaults) > 1 {
+		return "", errors.New("env accepts a variable name and at most one default")
+	}
+	if value, ok := os.LookupEnv(name); ok {
+		return value, nil
+	}
+	if len(defaults) == 1 {
+		return defaults[0], nil
+	}
+	return "", fmt.Errorf("environment variable %s is not set and has no default", name)
+}
+
+// renderQueryTemplate renders the query as a template, e.g. where id = {{env "GAME_ID"}};.
+func renderQueryTemplate(query string) (string, error) {
+	tmpl, err := template.New("query").Funcs(queryTemplateFuncs).Option("missingkey=error").Parse(query)
+	if err != nil {
+		return "", fmt.Errorf("invalid query template: %s", err.Error())
+	}
+
+	rendered := &strings.Builder{}
+	err = tmpl.Execute(rendered, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to render the query template: %s", err.Error())
+	}
+	return rendered.String(), nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requestTrace records the timings of the network phases of a request with httptrace.
+type requestTrace struct {
+	mu    sync.Mutex
+	start time.Time
+
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	firstByte                 time.Time
+	reused                    bool
+}
+
+// newRequestTrace starts the trace of a request.
+func newRequestTrace() *requestTrace {
+	return &requestTrace{start: time.Now()}
+}
+
+// clientTrace returns the hooks recording the timings of the trace.
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	record := func(at *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*at = time.Now()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { record(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { record(&t.dnsDone) },
+		ConnectStart:         func(string, string) { record(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { record(&t.connectDone) },
+		TLSHandshakeStart:    func() { record(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(&t.tlsDone) },
+		GotFirstResponseByte: func() { record(&t.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+		},
+	}
+}
+
+// String summarizes the timings of the phases the request went through, where a reused
+// connection skips the DNS lookup, connect and TLS handshake.
+func (t *requestTrace) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	phases := []string{}
+	phase := func(name string, start time.Time, done time.Time) {
+		if !start.IsZero() && !done.IsZero() {
+			phases = append(phases, fmt.Sprintf("%s %s", name, done.Sub(start).Round(time.Microsecond)))
+		}
+	}
+	phase("dns", t.dnsStart, t.dnsDone)
+	phase("connect", t.connectStart, t.connectDone)
+	phase("tls", t.tlsStart, t.tlsDone)
+	phase("first byte", t.start, t.firstByte)
+	if t.reused {
+		phases = append(phases, "reused connection")
+	}
+	return strings.Join(phases, ", ")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	// The default tuning of the connections reused by the transport, which keeps enough idle
+	// connections to the IGDB for concurrent batches and pages.
+	DEFAULT_MAX_IDLE_CONNS          = 100
+	DEFAULT_MAX_IDLE_CONNS_PER_HOST = 16
+	DEFAULT_IDLE_CONN_TIMEOUT       = 90 * time.Second
+
+	// The default timeouts of establishing connections, matching those of the default transport.
+	DEFAULT_DIAL_TIMEOUT          = 30 * time.Second
+	DEFAULT_DIAL_KEEP_ALIVE       = 30 * time.Second
+	DEFAULT_TLS_HANDSHAKE_TIMEOUT = 10 * time.Second
+)
+
+// newHTTPClient instantiates the HTTP client shared by every request the program makes, so
+// that the transport settings apply uniformly to authentication and queries.
+func newHTTPClient(opts *options) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = opts.maxIdleConns
+	transport.MaxIdleConnsPerHost = opts.maxIdleConnsPerHost
+	transport.IdleConnTimeout = opts.idleConnTimeout
+
+	// Connecting, the TLS handshake and awaiting the response headers are each timed out
+	// separately, where zero waits forever.
+	transport.DialContext = newDialer(opts).DialContext
+	transport.TLSHandshakeTimeout = opts.tlsHandshakeTimeout
+	transport.ResponseHeaderTimeout = opts.responseHeaderTimeout
+
+	// A non-nil, empty map of protocols disables the HTTP/2 upgrade.
+	transport.ForceAttemptHTTP2 = opts.http2
+	if !opts.http2 {
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	// Without an explicit proxy, the HTTP_PROXY and HTTPS_PROXY environment variables apply.
+	if opts.proxy != "" {
+		proxyURL, err := url.Parse(opts.proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %s", err.Error())
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	// Each request, including reading its response body, is timed out as a whole.
+	httpClient := &http.Client{Transport: transport, Timeout: opts.requestTimeout}
+
+	// Record every exchange through the transport, if requested.
+	if opts.record != "" {
+		httpClient.Transport = newRecordingTransport(transport, opts.record, opts.recordSecrets)
+	}
+
+	return httpClient, nil
+}
+
+// newDialer instantiates the dialer of the transport's connections, timing out connecting.
+func newDialer(opts *options) *net.Dialer {
+	return &net.Dialer{Timeout: opts.dialTimeout, KeepAlive: DEFAULT_DIAL_KEEP_ALIVE}
+}
+
+// newTLSConfig instantiates the TLS configuration, which fully verifies certificates by default.
+func newTLSConfig(opts *options) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	// Trust the custom CA in addition to the system's, e.g. for a corporate proxy.
+	if opts.caCert != "" {
+		caCert, err := os.ReadFile(opts.caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %s", err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("CA certificate contains no PEM encoded certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.insecureSkipVerify {
+		fmt.Fprintf(os.Stderr, "WARNING: TLS certificate verification is disabled, connections are vulnerable to interception\n")
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	return tlsConfig, nil
+}
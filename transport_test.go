@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientAppliesTimeouts(t *testing.T) {
+	tests := []struct {
+		name                                                                    string
+		args                                                                    []string
+		dialTimeout, tlsHandshakeTimeout, responseHeaderTimeout, requestTimeout time.Duration
+	}{
+		{
+			name:                "defaults",
+			dialTimeout:         DEFAULT_DIAL_TIMEOUT,
+			tlsHandshakeTimeout: DEFAULT_TLS_HANDSHAKE_TIMEOUT,
+		},
+		{
+			name:                  "configured",
+			args:                  []string{"-dial-timeout", "3s", "-tls-handshake-timeout", "4s", "-response-header-timeout", "5s", "-request-timeout", "6s"},
+			dialTimeout:           3 * time.Second,
+			tlsHandshakeTimeout:   4 * time.Second,
+			responseHeaderTimeout: 5 * time.Second,
+			requestTimeout:        6 * time.Second,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := parseTestOptions(t, test.args...)
+			httpClient, err := newHTTPClient(opts)
+			if err != nil {
+				t.Fatalf("failed to instantiate the HTTP client: %s", err.Error())
+			}
+
+			if dialer := newDialer(opts); dialer.Timeout != test.dialTimeout {
+				t.Errorf("expected a dial timeout of %s, got %s", test.dialTimeout, dialer.Timeout)
+			}
+			transport, ok := httpClient.Transport.(*http.Transport)
+			if !ok {
+				t.Fatalf("expected an *http.Transport, got %T", httpClient.Transport)
+			}
+			if transport.TLSHandshakeTimeout != test.tlsHandshakeTimeout {
+				t.Errorf("expected a TLS handshake timeout of %s, got %s", test.tlsHandshakeTimeout, transport.TLSHandshakeTimeout)
+			}
+			if transport.ResponseHeaderTimeout != test.responseHeaderTimeout {
+				t.Errorf("expected a response header timeout of %s, got %s", test.responseHeaderTimeout, transport.ResponseHeaderTimeout)
+			}
+			if transport.DialContext == nil {
+				t.Error("expected the transport to dial with the configured dialer")
+			}
+			if httpClient.Timeout != test.requestTimeout {
+				t.Errorf("expected a request timeout of %s, got %s", test.requestTimeout, httpClient.Timeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// The fields modeled by the typed endpoint helpers.
+	PLATFORM_FIELDS = "id,name,abbreviation,alternative_name,generation,platform_family,slug,url"
+	GENRE_FIELDS    = "id,name,slug,url"
+)
+
+// Platform is a record of the IGDB platforms endpoint.
+type Platform struct {
+	ID              int64  `json:"id"`
+	Name            string `json:"name"`
+	Abbreviation    string `json:"abbreviation,omitempty"`
+	AlternativeName string `json:"alternative_name,omitempty"`
+	Generation      int    `json:"generation,omitempty"`
+	PlatformFamily  int64  `json:"platform_family,omitempty"`
+	Slug            string `json:"slug,omitempty"`
+	URL             string `json:"url,omitempty"`
+}
+
+// Genre is a record of the IGDB genres endpoint.
+type Genre struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// QueryPlatforms queries the platforms endpoint, selecting the modeled fields unless the query selects its own.
+func (d *DatabaseClient) QueryPlatforms(ctx context.Context, query string) ([]Platform, error) {
+	platforms := []Platform{}
+	err := d.queryInto(ctx, "platforms", query, PLATFORM_FIELDS, &platforms)
+	if err != nil {
+		return nil, err
+	}
+	return platforms, nil
+}
+
+// QueryGenres queries the genres endpoint, selecting the modeled fields unless the query selects its own.
+func (d *DatabaseClient) QueryGenres(ctx context.Context, query string) ([]Genre, error) {
+	genres := []Genre{}
+	err := d.queryInto(ctx, "genres", query, GENRE_FIELDS, &genres)
+	if err != nil {
+		return nil, err
+	}
+	return genres, nil
+}
+
+// queryInto queries the endpoint and decodes the records into the given value, selecting the
+// default fields when the query doesn't select any.
+func (d *DatabaseClient) queryInto(ctx context.Context, endpoint string, query string, defaultFields string, v interface{}) error {
+	q, err := parseQuery(query)
+	if err != nil {
+		return fmt.Errorf("failed to parse query: %s", err.Error())
+	}
+	if _, ok := q.get("fields"); !ok {
+		q.set("fields", defaultFields)
+	}
+
+	result, err := d.QueryContext(ctx, endpoint, q.String())
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal([]byte(result), v)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %s", endpoint, err.Error())
+	}
+	return nil
+}
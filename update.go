@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Constants used for checking for newer releases of the program.
+	LATEST_RELEASE_URL = "https://api.github.com/repos/nickolasgough/gamers-console/releases/latest"
+	DEV_VERSION        = "dev"
+)
+
+// version is the version of the program, injected at build time via:
+// go build -ldflags "-X main.version=v1.2.3".
+var version = DEV_VERSION
+
+// latestRelease represents the JSON response body describing the latest release.
+type latestRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// getLatestRelease retrieves the latest published release of the program.
+func getLatestRelease(httpClient *http.Client) (*latestRelease, error) {
+	resp, err := httpClient.Get(LATEST_RELEASE_URL)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	release := &latestRelease{}
+	err = json.Unmarshal(respBytes, release)
+	if err != nil {
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// parseVersion parses a version of the form v1.2.3 into its numeric components.
+func parseVersion(v string) ([]int, error) {
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		// Ignore pre-release and build metadata suffixes, e.g. 1.2.3-rc1.
+		part = strings.SplitN(strings.SplitN(part, "-", 2)[0], "+", 2)[0]
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", v)
+		}
+		numbers[i] = number
+	}
+
+	return numbers, nil
+}
+
+// isNewerVersion reports whether the candidate version is newer than the current version.
+func isNewerVersion(current string, candidate string) (bool, error) {
+	currentNumbers, err := parseVersion(current)
+	if err != nil {
+		return false, err
+	}
+	candidateNumbers, err := parseVersion(candidate)
+	if err != nil {
+		return false, err
+	}
+
+	for i := 0; i < len(currentNumbers) || i < len(candidateNumbers); i++ {
+		var currentNumber, candidateNumber int
+		if i < len(currentNumbers) {
+			currentNumber = currentNumbers[i]
+		}
+		if i < len(candidateNumbers) {
+			candidateNumber = candidateNumbers[i]
+		}
+		if currentNumber != candidateNumber {
+			return candidateNumber > currentNumber, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkForUpdate prints a notice to stderr when a newer release than the running version exists.
+func checkForUpdate(httpClient *http.Client) {
+	release, err := getLatestRelease(httpClient)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to check for updates with error: %s\n", err.Error())
+		return
+	}
+
+	// Development builds have no version to compare against.
+	if version == DEV_VERSION {
+		fmt.Fprintf(os.Stderr, "Running a development build, the latest release is %s: %s\n", release.TagName, release.HTMLURL)
+		return
+	}
+
+	newer, err := isNewerVersion(version, release.TagName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to compare versions with error: %s\n", err.Error())
+		return
+	}
+	if newer {
+		fmt.Fprintf(os.Stderr, "A newer version is available: %s (running %s): %s\n", release.TagName, version, release.HTMLURL)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const (
+	// ANSI escape sequence moving the cursor home and clearing the screen.
+	CLEAR_SCREEN_SEQUENCE = "\033[H\033[2J"
+)
+
+// runWatch re-runs the query on the configured interval until interrupted.
+func runWatch(opts *options, databaseClient *DatabaseClient, endpoint string, query string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// When diffing, the first run is compared to the snapshot and later runs to their predecessor.
+	previous := ""
+	if opts.diff != "" {
+		snapshot, err := os.ReadFile(opts.diff)
+		if err != nil {
+			handleErr("failed to read the diff snapshot", err, BAD_USAGE_EXIT_CODE)
+		}
+		previous = string(snapshot)
+	}
+
+	// The rate limit is explained at most once per watch.
+	explained := false
+	for {
+		meta := newResultMeta(endpoint, query)
+		queryResult, err := submitQuery(ctx, opts, databaseClient, endpoint, query)
+		meta.rateLimit = databaseClient.LastRateLimitStatus()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if opts.clear {
+			writeStdout(CLEAR_SCREEN_SEQUENCE)
+		}
+		printfStdout("Every %s: %s %s\n\n", opts.watch, endpoint, time.Now().Format(time.RFC1123))
+
+		// Keep watching through failed runs, the next may succeed.
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to query the internet games database with error: %s\n", describeQueryErr(opts, err).Error())
+			if !explained {
+				explained = explainRateLimit(opts, databaseClient)
+			}
+		} else if opts.diff != "" {
+			err = printDiff(opts, previous, queryResult)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to diff the query result with error: %s\n", err.Error())
+			}
+			previous = queryResult
+		} else {
+			printResult(opts, meta, queryResult)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.watch):
+		}
+	}
+}